@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed templates static
+var embeddedAssets embed.FS
+
+// Templates holds every page template parsed once at startup.
+// With reload set, templates are parsed from fsys again on every render, which is what you want while editing them.
+type Templates struct {
+	fsys   fs.FS
+	reload bool
+	parsed map[string]*template.Template
+}
+
+func NewTemplates(fsys fs.FS, reload bool) (*Templates, error) {
+	t := &Templates{fsys: fsys, reload: reload, parsed: map[string]*template.Template{}}
+
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		tmpl, err := t.parse(name)
+		if err != nil {
+			return nil, err
+		}
+		t.parsed[name] = tmpl
+	}
+	return t, nil
+}
+
+func (t *Templates) parse(name string) (*template.Template, error) {
+	return template.ParseFS(t.fsys, name)
+}
+
+func (t *Templates) Execute(w io.Writer, name string, data any) error {
+	if t.reload {
+		tmpl, err := t.parse(name)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, data)
+	}
+
+	tmpl, ok := t.parsed[name]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	return tmpl.Execute(w, data)
+}
+
+// Serves static files with cache headers.
+// Embedded files never change while the server runs, so their ETags are computed once up front.
+// In dev mode files are read from disk and the browser is told to always revalidate.
+func staticHandler(fsys fs.FS, dev bool) (http.Handler, error) {
+	fileServer := http.FileServerFS(fsys)
+	if dev {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			fileServer.ServeHTTP(w, r)
+		}), nil
+	}
+
+	etags := map[string]string{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		etags[p] = `"` + hex.EncodeToString(sum[:8]) + `"`
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag, ok := etags[strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")]; ok {
+			// http.FileServer answers If-None-Match with 304 on its own once the ETag header is set
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		}
+		fileServer.ServeHTTP(w, r)
+	}), nil
+}
+
+// Picks the template and static file systems: embedded into the binary normally, straight from disk in dev mode
+func assetFS(cfg Config) (templates fs.FS, static fs.FS, err error) {
+	if cfg.Dev {
+		return os.DirFS(cfg.TemplatesDir), os.DirFS(cfg.StaticDir), nil
+	}
+	templates, err = fs.Sub(embeddedAssets, "templates")
+	if err != nil {
+		return nil, nil, err
+	}
+	static, err = fs.Sub(embeddedAssets, "static")
+	return templates, static, err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticHandlerETag(t *testing.T) {
+	fsys := fstest.MapFS{"css/style.css": {Data: []byte("body {}")}}
+	handler, err := staticHandler(fsys, false)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/css/style.css", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "max-age")
+
+	req := httptest.NewRequest("GET", "/css/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+}
+
+func TestStaticHandlerDevMode(t *testing.T) {
+	fsys := fstest.MapFS{"css/style.css": {Data: []byte("body {}")}}
+	handler, err := staticHandler(fsys, true)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/css/style.css", nil))
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	assert.Empty(t, rr.Header().Get("ETag"))
+}
+
+func TestTemplatesReload(t *testing.T) {
+	fsys := fstest.MapFS{"page.html": {Data: []byte("v1")}}
+	templates, err := NewTemplates(fsys, true)
+	assert.NoError(t, err)
+
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("v2")}
+	var out bytes.Buffer
+	assert.NoError(t, templates.Execute(&out, "page.html", nil))
+	assert.Equal(t, "v2", out.String())
+}
+
+func TestEmbeddedTemplatesParse(t *testing.T) {
+	templatesFS, _, err := assetFS(DefaultConfig())
+	assert.NoError(t, err)
+	templates, err := NewTemplates(templatesFS, false)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"index.html", "login-page.html", "table.html"} {
+		assert.Contains(t, templates.parsed, name)
+	}
+}
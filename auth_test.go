@@ -33,7 +33,7 @@ func TestIsAutorised(t *testing.T) {
 		t.Fatalf("Failed to insert test data: %v", err)
 	}
 
-	ctx := &Context{db: db}
+	ctx := newTestContext(db)
 
 	tests := []struct {
 		name           string
@@ -92,7 +92,7 @@ func TestLogout(t *testing.T) {
 		t.Fatalf("Failed to insert test data: %v", err)
 	}
 
-	ctx := &Context{db: db}
+	ctx := newTestContext(db)
 
 	tests := []struct {
 		name      string
@@ -180,7 +180,7 @@ func TestLoginForm(t *testing.T) {
 	}
 	defer db.Close()
 
-	ctx := &Context{db: db}
+	ctx := newTestContext(db)
 
 	tests := []struct {
 		name           string
@@ -208,13 +208,13 @@ func TestLoginForm(t *testing.T) {
 			username:       "testuser",
 			password:       "password",
 			expectedStatus: http.StatusOK,
-			expectedBody:   "<p>Logged in!</p>",
+			expectedBody:   responseLoggedInSuccess,
 		},
 		{
 			name:           "Invalid password",
 			username:       "testuser",
 			password:       "wrongpassword",
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   "<p>User testuser esists, but the password doesn't match</p>",
 		},
 		{
@@ -222,7 +222,7 @@ func TestLoginForm(t *testing.T) {
 			username:       "newuser",
 			password:       "newpassword",
 			expectedStatus: http.StatusOK,
-			expectedBody:   "<p>Registration successful! Make sure to remember your password, because there is no way to restore it</p>",
+			expectedBody:   responseRegistrationSuccess,
 		},
 	}
 
@@ -240,7 +240,7 @@ func TestLoginForm(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 
-			ctx.loginForm(rr, req)
+			appHandler(ctx.loginForm).ServeHTTP(rr, req)
 
 			if rr.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
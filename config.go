@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds everything that can differ between deployments.
+// Values are resolved in this order, later ones win: defaults, config file, environment variables, command-line flags.
+type Config struct {
+	Addr string `toml:"addr" yaml:"addr"`
+	// DatabaseDriver is "sqlite", which uses DatabasePath, or "postgres", which uses PostgresURL
+	DatabaseDriver string `toml:"database_driver" yaml:"database_driver"`
+	DatabasePath   string `toml:"database_path" yaml:"database_path"`
+	PostgresURL    string `toml:"postgres_url" yaml:"postgres_url"`
+	StaticDir      string `toml:"static_dir" yaml:"static_dir"`
+	TemplatesDir   string `toml:"templates_dir" yaml:"templates_dir"`
+	BcryptCost     int    `toml:"bcrypt_cost" yaml:"bcrypt_cost"`
+	// Dev serves templates and static files from StaticDir and TemplatesDir instead of the copies embedded in the binary
+	Dev bool `toml:"dev" yaml:"dev"`
+
+	ReadTimeout  time.Duration `toml:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `toml:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  time.Duration `toml:"idle_timeout" yaml:"idle_timeout"`
+	// How long in-flight requests get to finish after SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout" yaml:"shutdown_timeout"`
+
+	// Largest pronunciation recording that can be uploaded, in bytes
+	MaxAudioSize int64 `toml:"max_audio_size" yaml:"max_audio_size"`
+	// Largest picture that can be uploaded, in bytes, and how much the pictures of one user can take after re-encoding
+	MaxImageSize int64 `toml:"max_image_size" yaml:"max_image_size"`
+	ImageQuota   int64 `toml:"image_quota" yaml:"image_quota"`
+	// Largest subtitle file or EPUB book that can be uploaded to find unknown words in, in bytes
+	MaxTextFileSize int64 `toml:"max_text_file_size" yaml:"max_text_file_size"`
+	// Largest vocab.db of a Kindle that can be uploaded to import its words, in bytes
+	MaxKindleDBSize int64 `toml:"max_kindle_db_size" yaml:"max_kindle_db_size"`
+	// The espeak-ng program that generates pronunciation for words without a recording, empty turns it off
+	EspeakPath string `toml:"espeak_path" yaml:"espeak_path"`
+	// Where generated pronunciation is kept so it is only made once, empty turns caching off
+	SpeechCacheDir string `toml:"speech_cache_dir" yaml:"speech_cache_dir"`
+	// StarDict and dictd dictionaries to look up words in while adding them, read at startup
+	DictionaryDir string `toml:"dictionary_dir" yaml:"dictionary_dir"`
+	// A LibreTranslate server that suggests translations the dictionaries don't have, empty turns it off
+	TranslateURL    string `toml:"translate_url" yaml:"translate_url"`
+	TranslateAPIKey string `toml:"translate_api_key" yaml:"translate_api_key"`
+}
+
+const envPrefix = "WORDSEARCH_"
+
+func DefaultConfig() Config {
+	return Config{
+		Addr:           ":8080",
+		DatabaseDriver: "sqlite",
+		DatabasePath:   "./words.db",
+		StaticDir:      "./static",
+		TemplatesDir:   "./templates",
+		BcryptCost:     12,
+
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		IdleTimeout:     2 * time.Minute,
+		ShutdownTimeout: 15 * time.Second,
+
+		MaxAudioSize:    1 << 20,
+		MaxImageSize:    5 << 20,
+		ImageQuota:      50 << 20,
+		MaxTextFileSize: 10 << 20,
+		MaxKindleDBSize: 32 << 20,
+		EspeakPath:      "espeak-ng",
+		SpeechCacheDir:  "./speech-cache",
+		DictionaryDir:   "./dictionaries",
+	}
+}
+
+// Builds the config from command-line arguments (without the program name and command) and the environment.
+// getenv is os.LookupEnv outside of tests. Arguments left after the flags are returned as they are.
+func LoadConfig(args []string, getenv func(string) (string, bool)) (Config, []string, error) {
+	cfg := DefaultConfig()
+
+	fs := flag.NewFlagSet("wordsearch", flag.ContinueOnError)
+	configEnv, _ := getenv(envPrefix + "CONFIG")
+	configPath := fs.String("config", configEnv, "path to a .toml or .yaml config file")
+	addr := fs.String("addr", "", "address to listen on, e.g. :8080")
+	dbDriver := fs.String("db-driver", "", "database to use: sqlite or postgres")
+	dbPath := fs.String("db", "", "path to the SQLite database file")
+	postgresURL := fs.String("postgres-url", "", "PostgreSQL connection string")
+	staticDir := fs.String("static", "", "directory with static files")
+	templatesDir := fs.String("templates", "", "directory with HTML templates")
+	bcryptCost := fs.Int("bcrypt-cost", 0, "bcrypt cost used when hashing new passwords")
+	dev := fs.Bool("dev", false, "reload templates and static files from disk")
+	readTimeout := fs.Duration("read-timeout", 0, "maximum duration for reading a request")
+	writeTimeout := fs.Duration("write-timeout", 0, "maximum duration for writing a response")
+	idleTimeout := fs.Duration("idle-timeout", 0, "how long keep-alive connections stay open")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "how long in-flight requests get to finish on shutdown")
+	maxAudioSize := fs.Int64("max-audio-size", 0, "largest pronunciation recording that can be uploaded, in bytes")
+	maxImageSize := fs.Int64("max-image-size", 0, "largest picture that can be uploaded, in bytes")
+	imageQuota := fs.Int64("image-quota", 0, "bytes the pictures of one user can take")
+	maxTextFileSize := fs.Int64("max-text-file-size", 0, "largest subtitle file or EPUB book that can be uploaded, in bytes")
+	maxKindleDBSize := fs.Int64("max-kindle-db-size", 0, "largest Kindle vocab.db that can be uploaded, in bytes")
+	espeakPath := fs.String("espeak", "", "espeak-ng program for generated pronunciation, empty turns it off")
+	speechCacheDir := fs.String("speech-cache", "", "directory for generated pronunciation, empty turns caching off")
+	dictionaryDir := fs.String("dictionaries", "", "directory with StarDict and dictd dictionaries")
+	translateURL := fs.String("translate-url", "", "LibreTranslate server for suggested translations, empty turns it off")
+	translateAPIKey := fs.String("translate-api-key", "", "API key for the LibreTranslate server")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return cfg, nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(getenv); err != nil {
+		return cfg, nil, err
+	}
+
+	// Only flags that were actually passed override what we have so far
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "db-driver":
+			cfg.DatabaseDriver = *dbDriver
+		case "db":
+			cfg.DatabasePath = *dbPath
+		case "postgres-url":
+			cfg.PostgresURL = *postgresURL
+		case "static":
+			cfg.StaticDir = *staticDir
+		case "templates":
+			cfg.TemplatesDir = *templatesDir
+		case "bcrypt-cost":
+			cfg.BcryptCost = *bcryptCost
+		case "dev":
+			cfg.Dev = *dev
+		case "read-timeout":
+			cfg.ReadTimeout = *readTimeout
+		case "write-timeout":
+			cfg.WriteTimeout = *writeTimeout
+		case "idle-timeout":
+			cfg.IdleTimeout = *idleTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "max-audio-size":
+			cfg.MaxAudioSize = *maxAudioSize
+		case "max-image-size":
+			cfg.MaxImageSize = *maxImageSize
+		case "image-quota":
+			cfg.ImageQuota = *imageQuota
+		case "max-text-file-size":
+			cfg.MaxTextFileSize = *maxTextFileSize
+		case "max-kindle-db-size":
+			cfg.MaxKindleDBSize = *maxKindleDBSize
+		case "espeak":
+			cfg.EspeakPath = *espeakPath
+		case "speech-cache":
+			cfg.SpeechCacheDir = *speechCacheDir
+		case "dictionaries":
+			cfg.DictionaryDir = *dictionaryDir
+		case "translate-url":
+			cfg.TranslateURL = *translateURL
+		case "translate-api-key":
+			cfg.TranslateAPIKey = *translateAPIKey
+		}
+	})
+
+	return cfg, fs.Args(), cfg.Validate()
+}
+
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config file %s: unknown format, use .toml or .yaml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Variables that are set override the setting, even when they are empty
+func (cfg *Config) loadEnv(getenv func(string) (string, bool)) error {
+	if v, ok := getenv(envPrefix + "ADDR"); ok {
+		cfg.Addr = v
+	}
+	if v, ok := getenv(envPrefix + "DATABASE_DRIVER"); ok {
+		cfg.DatabaseDriver = v
+	}
+	if v, ok := getenv(envPrefix + "DATABASE_PATH"); ok {
+		cfg.DatabasePath = v
+	}
+	if v, ok := getenv(envPrefix + "POSTGRES_URL"); ok {
+		cfg.PostgresURL = v
+	}
+	if v, ok := getenv(envPrefix + "STATIC_DIR"); ok {
+		cfg.StaticDir = v
+	}
+	if v, ok := getenv(envPrefix + "TEMPLATES_DIR"); ok {
+		cfg.TemplatesDir = v
+	}
+	if v, ok := getenv(envPrefix + "BCRYPT_COST"); ok {
+		cost, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sBCRYPT_COST: %w", envPrefix, err)
+		}
+		cfg.BcryptCost = cost
+	}
+	if v, ok := getenv(envPrefix + "DEV"); ok {
+		dev, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sDEV: %w", envPrefix, err)
+		}
+		cfg.Dev = dev
+	}
+	if v, ok := getenv(envPrefix + "ESPEAK_PATH"); ok {
+		cfg.EspeakPath = v
+	}
+	if v, ok := getenv(envPrefix + "SPEECH_CACHE_DIR"); ok {
+		cfg.SpeechCacheDir = v
+	}
+	if v, ok := getenv(envPrefix + "DICTIONARY_DIR"); ok {
+		cfg.DictionaryDir = v
+	}
+	if v, ok := getenv(envPrefix + "TRANSLATE_URL"); ok {
+		cfg.TranslateURL = v
+	}
+	if v, ok := getenv(envPrefix + "TRANSLATE_API_KEY"); ok {
+		cfg.TranslateAPIKey = v
+	}
+	sizes := map[string]*int64{
+		"MAX_AUDIO_SIZE":     &cfg.MaxAudioSize,
+		"MAX_IMAGE_SIZE":     &cfg.MaxImageSize,
+		"IMAGE_QUOTA":        &cfg.ImageQuota,
+		"MAX_TEXT_FILE_SIZE": &cfg.MaxTextFileSize,
+		"MAX_KINDLE_DB_SIZE": &cfg.MaxKindleDBSize,
+	}
+	for name, dst := range sizes {
+		if v, ok := getenv(envPrefix + name); ok {
+			size, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", envPrefix, name, err)
+			}
+			*dst = size
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"READ_TIMEOUT":     &cfg.ReadTimeout,
+		"WRITE_TIMEOUT":    &cfg.WriteTimeout,
+		"IDLE_TIMEOUT":     &cfg.IdleTimeout,
+		"SHUTDOWN_TIMEOUT": &cfg.ShutdownTimeout,
+	}
+	for name, dst := range durations {
+		if v, ok := getenv(envPrefix + name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", envPrefix, name, err)
+			}
+			*dst = d
+		}
+	}
+	return nil
+}
+
+// Checks that the config makes sense before we start the server with it
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr %q: %w", cfg.Addr, err))
+	}
+	switch cfg.DatabaseDriver {
+	case "sqlite":
+		if cfg.DatabasePath == "" {
+			errs = append(errs, errors.New("database_path can't be empty"))
+		}
+	case "postgres":
+		if cfg.PostgresURL == "" {
+			errs = append(errs, errors.New("postgres_url is required for the postgres driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database_driver must be sqlite or postgres, got %q", cfg.DatabaseDriver))
+	}
+	if cfg.StaticDir == "" {
+		errs = append(errs, errors.New("static_dir can't be empty"))
+	}
+	if cfg.TemplatesDir == "" {
+		errs = append(errs, errors.New("templates_dir can't be empty"))
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("bcrypt_cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost))
+	}
+	if cfg.ReadTimeout <= 0 || cfg.WriteTimeout <= 0 || cfg.IdleTimeout <= 0 || cfg.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("timeouts must be positive"))
+	}
+	if cfg.MaxAudioSize <= 0 || cfg.MaxImageSize <= 0 || cfg.ImageQuota <= 0 || cfg.MaxTextFileSize <= 0 || cfg.MaxKindleDBSize <= 0 {
+		errs = append(errs, errors.New("max_audio_size, max_image_size, image_quota, max_text_file_size and max_kindle_db_size must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, _, err := LoadConfig(nil, fakeEnv(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	tomlPath := filepath.Join(dir, "wordsearch.toml")
+	os.WriteFile(tomlPath, []byte(`
+addr = ":9000"
+database_path = "/var/lib/wordsearch/file.db"
+bcrypt_cost = 10
+shutdown_timeout = "5s"
+`), 0o644)
+
+	env := fakeEnv(map[string]string{
+		"WORDSEARCH_CONFIG":        tomlPath,
+		"WORDSEARCH_DATABASE_PATH": "/tmp/env.db",
+		"WORDSEARCH_BCRYPT_COST":   "11",
+	})
+
+	cfg, _, err := LoadConfig([]string{"-bcrypt-cost", "13"}, env)
+	assert.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Addr, "file overrides default")
+	assert.Equal(t, "/tmp/env.db", cfg.DatabasePath, "env overrides file")
+	assert.Equal(t, 13, cfg.BcryptCost, "flag overrides env")
+	assert.Equal(t, "./templates", cfg.TemplatesDir, "untouched values keep defaults")
+	assert.Equal(t, 5*time.Second, cfg.ShutdownTimeout)
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	yamlPath := filepath.Join(t.TempDir(), "wordsearch.yaml")
+	os.WriteFile(yamlPath, []byte("addr: 127.0.0.1:8081\nstatic_dir: /srv/static\n"), 0o644)
+
+	cfg, _, err := LoadConfig([]string{"-config", yamlPath}, fakeEnv(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8081", cfg.Addr)
+	assert.Equal(t, "/srv/static", cfg.StaticDir)
+}
+
+func TestLoadConfigEmptyEnvTurnsOff(t *testing.T) {
+	tomlPath := filepath.Join(t.TempDir(), "wordsearch.toml")
+	os.WriteFile(tomlPath, []byte(`
+espeak_path = "/opt/espeak-ng"
+speech_cache_dir = "/var/cache/speech"
+translate_url = "http://localhost:5000"
+`), 0o644)
+
+	for _, tt := range []struct {
+		name string
+		get  func(Config) string
+	}{
+		{"ESPEAK_PATH", func(cfg Config) string { return cfg.EspeakPath }},
+		{"SPEECH_CACHE_DIR", func(cfg Config) string { return cfg.SpeechCacheDir }},
+		{"TRANSLATE_URL", func(cfg Config) string { return cfg.TranslateURL }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := LoadConfig([]string{"-config", tomlPath}, fakeEnv(nil))
+			assert.NoError(t, err)
+			assert.NotEmpty(t, tt.get(cfg), "set by the file")
+
+			cfg, _, err = LoadConfig([]string{"-config", tomlPath}, fakeEnv(map[string]string{envPrefix + tt.name: ""}))
+			assert.NoError(t, err)
+			assert.Empty(t, tt.get(cfg), "an empty variable turns it off")
+		})
+	}
+}
+
+func TestLoadConfigKindleSize(t *testing.T) {
+	tomlPath := filepath.Join(t.TempDir(), "wordsearch.toml")
+	os.WriteFile(tomlPath, []byte("max_kindle_db_size = 1000\n"), 0o644)
+
+	cfg, _, err := LoadConfig([]string{"-config", tomlPath}, fakeEnv(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), cfg.MaxKindleDBSize)
+
+	env := fakeEnv(map[string]string{"WORDSEARCH_MAX_KINDLE_DB_SIZE": "2000"})
+	cfg, _, err = LoadConfig([]string{"-config", tomlPath}, env)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), cfg.MaxKindleDBSize, "env overrides file")
+
+	cfg, _, err = LoadConfig([]string{"-config", tomlPath, "-max-kindle-db-size", "3000"}, env)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3000), cfg.MaxKindleDBSize, "flag overrides env")
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{name: "Address without port", args: []string{"-addr", "localhost"}},
+		{name: "Bcrypt cost too low", args: []string{"-bcrypt-cost", "1"}},
+		{name: "Bcrypt cost not a number", env: map[string]string{"WORDSEARCH_BCRYPT_COST": "lots"}},
+		{name: "Empty database path", args: []string{"-db", ""}},
+		{name: "Empty database path from the environment", env: map[string]string{"WORDSEARCH_DATABASE_PATH": ""}},
+		{name: "Zero timeout", args: []string{"-write-timeout", "0s"}},
+		{name: "Bad timeout", env: map[string]string{"WORDSEARCH_IDLE_TIMEOUT": "forever"}},
+		{name: "No audio size", args: []string{"-max-audio-size", "0"}},
+		{name: "Negative image quota", env: map[string]string{"WORDSEARCH_IMAGE_QUOTA": "-1"}},
+		{name: "No Kindle size", args: []string{"-max-kindle-db-size", "0"}},
+		{name: "Negative Kindle size", env: map[string]string{"WORDSEARCH_MAX_KINDLE_DB_SIZE": "-1"}},
+		{name: "Missing config file", args: []string{"-config", "does-not-exist.toml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := LoadConfig(tt.args, fakeEnv(tt.env))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+var responseBadConjugation = `<p>Verb forms can only contain letters, spaces, ' and -, hulpwerkwoord must be hebben, zijn or hebben/zijn</p>`
+var responseNotAVerb = `<p>Only verbs can have a conjugation, set the woordsoort to ww first</p>`
+
+// Conjugation holds the forms of a Dutch verb that can't be guessed reliably
+type Conjugation struct {
+	// Tegenwoordige tijd
+	Ik       string
+	Jij      string
+	Hij      string
+	Meervoud string // wij, jullie, zij
+
+	// Onvoltooid verleden tijd (imperfectum)
+	VerledenEnkelvoud string
+	VerledenMeervoud  string
+
+	VoltooidDeelwoord string
+	Hulpwerkwoord     string // hebben, zijn or hebben/zijn
+}
+
+// Woordsoort is free text, these are the ways people write down that a word is a verb
+var verbWordTypes = map[string]bool{
+	"ww":        true,
+	"werkwoord": true,
+	"verb":      true,
+	"v":         true,
+}
+
+// IsVerb is isVerb for templates
+func (w Word) IsVerb() bool {
+	return isVerb(w)
+}
+
+func isVerb(word Word) bool {
+	for _, sense := range word.Senses {
+		if verbWordTypes[strings.ToLower(strings.Trim(sense.Woordsoort, " ."))] {
+			return true
+		}
+	}
+	return false
+}
+
+// Every form in the order it is shown and searched
+func (c Conjugation) forms() []string {
+	return []string{c.Ik, c.Jij, c.Hij, c.Meervoud, c.VerledenEnkelvoud, c.VerledenMeervoud, c.VoltooidDeelwoord}
+}
+
+func (c Conjugation) isEmpty() bool {
+	return c == Conjugation{}
+}
+
+func validateConjugation(c Conjugation) error {
+	switch c.Hulpwerkwoord {
+	case "", "hebben", "zijn", "hebben/zijn":
+	default:
+		return fmt.Errorf("unknown auxiliary %q", c.Hulpwerkwoord)
+	}
+	for _, form := range c.forms() {
+		if !isVerbForm(form) {
+			return fmt.Errorf("%q doesn't look like a verb form", form)
+		}
+	}
+	return nil
+}
+
+// Like isWordForm, but with spaces for separable verbs ("ik sta op")
+func isVerbForm(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != ' ' && r != '\'' && r != '’' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func conjugationFromForm(r *http.Request) Conjugation {
+	field := func(name string) string {
+		return strings.TrimSpace(r.PostFormValue(name))
+	}
+	return Conjugation{
+		Ik:                field("ik"),
+		Jij:               field("jij"),
+		Hij:               field("hij"),
+		Meervoud:          field("meervoud"),
+		VerledenEnkelvoud: field("verleden_enkelvoud"),
+		VerledenMeervoud:  field("verleden_meervoud"),
+		VoltooidDeelwoord: field("voltooid_deelwoord"),
+		Hulpwerkwoord:     field("hulpwerkwoord"),
+	}
+}
+
+// Sends the edit form for the conjugation of a saved verb
+func (c *Context) conjugationForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	word, err := c.store.Word(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if !isVerb(word) {
+		return newHTTPError(http.StatusBadRequest, responseNotAVerb, nil)
+	}
+
+	data := struct {
+		Word        Word
+		Conjugation Conjugation
+		Suggested   bool
+	}{Word: word}
+	if word.Vervoeging != nil {
+		data.Conjugation = *word.Vervoeging
+	} else if list.IsDutch() {
+		data.Conjugation, data.Suggested = suggestConjugation(word.Woord)
+	}
+	return c.templates.Execute(w, "conjugation-form.html", data)
+}
+
+// Saves the rule-based conjugation ticked in the add form, unless the verb already has one
+func (c *Context) saveSuggestedConjugation(userID, listID int, newWord Word) error {
+	if !isVerb(newWord) {
+		return nil
+	}
+	conjugation, ok := suggestConjugation(newWord.Woord)
+	if !ok {
+		return nil
+	}
+	saved, err := c.store.Word(userID, listID, newWord.Woord)
+	if err != nil || saved.Vervoeging != nil {
+		return err
+	}
+	return c.store.SaveConjugation(userID, listID, newWord.Woord, conjugation)
+}
+
+func (c *Context) saveConjugation(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	conjugation := conjugationFromForm(r)
+	if err := validateConjugation(conjugation); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadConjugation, err)
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	word, err := c.store.Word(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if !isVerb(word) {
+		return newHTTPError(http.StatusBadRequest, responseNotAVerb, nil)
+	}
+
+	return c.store.SaveConjugation(user_id, list.ID, word.Woord, conjugation)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVerb(t *testing.T) {
+	assert.True(t, isVerb(Word{Senses: []Sense{{Woordsoort: "ww"}}}))
+	assert.True(t, isVerb(Word{Senses: []Sense{{Woordsoort: "zn"}, {Woordsoort: "Werkwoord"}}}))
+	assert.True(t, isVerb(Word{Senses: []Sense{{Woordsoort: "ww."}}}))
+	assert.False(t, isVerb(Word{Senses: []Sense{{Woordsoort: "zn"}}}))
+	assert.False(t, isVerb(Word{}))
+}
+
+func TestValidateConjugation(t *testing.T) {
+	tests := []struct {
+		name        string
+		conjugation Conjugation
+		valid       bool
+	}{
+		{name: "Empty", conjugation: Conjugation{}, valid: true},
+		{name: "Separable verb", conjugation: Conjugation{Ik: "sta op", VoltooidDeelwoord: "opgestaan", Hulpwerkwoord: "zijn"}, valid: true},
+		{name: "Both auxiliaries", conjugation: Conjugation{Hulpwerkwoord: "hebben/zijn"}, valid: true},
+		{name: "Unknown auxiliary", conjugation: Conjugation{Hulpwerkwoord: "worden"}, valid: false},
+		{name: "Markup", conjugation: Conjugation{Hij: "<i>loopt</i>"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConjugation(tt.conjugation)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConjugationHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	db.Exec("INSERT INTO words (user_id, list_id, word, pronunciation) VALUES (1, 1, 'werken', '')")
+	db.Exec("INSERT INTO senses (word_id, position, part_of_speech, translation) VALUES (1, 1, 'ww', 'to work')")
+	db.Exec("INSERT INTO words (user_id, list_id, word, pronunciation) VALUES (1, 1, 'huis', '')")
+	db.Exec("INSERT INTO senses (word_id, position, part_of_speech, translation) VALUES (2, 1, 'zn', 'house')")
+
+	router := http.NewServeMux()
+	router.Handle("GET /conjugation/{woord}", appHandler(c.conjugationForm))
+	router.Handle("POST /conjugation/{woord}", appHandler(c.saveConjugation))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/conjugation/werken", "ik=werk&verleden_enkelvoud=werkte&voltooid_deelwoord=gewerkt&hulpwerkwoord=hebben")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = do("GET", "/conjugation/werken", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `value="werkte"`)
+	assert.Contains(t, rr.Body.String(), `<option value="hebben" selected>`)
+
+	table, err := c.renderWordsTable(WordFilter{Search: "gewerkt"}, tableView{}, 1)
+	assert.NoError(t, err)
+	assert.Contains(t, string(table), "vervoeging")
+	assert.Contains(t, string(table), "hebben gewerkt")
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/conjugation/huis", "ik=huis").Code, "nouns have no conjugation")
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/conjugation/werken", "hulpwerkwoord=worden").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/conjugation/rennen", "").Code)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/Svuvi/wordsearch/dictionary"
+)
+
+// How many headwords the add form offers while typing
+const completions = 10
+
+// Dictionaries are the offline dictionaries found at startup
+type Dictionaries []*dictionary.Dictionary
+
+// Opens the dictionaries in dir, a missing directory means there are none
+func loadDictionaries(dir string) (Dictionaries, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	dictionaries, err := dictionary.OpenDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dictionaries {
+		log.Printf("Loaded dictionary %s with %d headwords", d.Name, d.Len())
+	}
+	return dictionaries, nil
+}
+
+// Whether a language of a dictionary is the language of a list, an unknown one matches any list
+func sameLanguage(dictionaryLanguage, listLanguage string) bool {
+	if dictionaryLanguage == "" {
+		return true
+	}
+	base, err := language.ParseBase(dictionaryLanguage)
+	return err == nil && base.String() == baseLanguage(listLanguage)
+}
+
+// The dictionaries that translate from the language of the list into its translation language
+func (ds Dictionaries) forList(list List) Dictionaries {
+	var matching Dictionaries
+	for _, d := range ds {
+		if sameLanguage(d.From, list.SourceLanguage) && sameLanguage(d.To, list.TargetLanguage) {
+			matching = append(matching, d)
+		}
+	}
+	return matching
+}
+
+// The first entry for the word in any dictionary of the list
+func (ds Dictionaries) lookup(list List, word string) (dictionary.Entry, bool) {
+	if word == "" {
+		return dictionary.Entry{}, false
+	}
+	for _, d := range ds.forList(list) {
+		if entries := d.Lookup(word); len(entries) > 0 {
+			return entries[0], true
+		}
+	}
+	return dictionary.Entry{}, false
+}
+
+// Up to n headwords starting with prefix from all dictionaries of the list, in alphabetical order
+func (ds Dictionaries) complete(list List, prefix string, n int) []string {
+	seen := map[string]bool{}
+	var headwords []string
+	for _, d := range ds.forList(list) {
+		for _, headword := range d.Complete(prefix, n) {
+			if !seen[headword] {
+				seen[headword] = true
+				headwords = append(headwords, headword)
+			}
+		}
+	}
+	sort.Slice(headwords, func(i, j int) bool { return strings.ToLower(headwords[i]) < strings.ToLower(headwords[j]) })
+	if len(headwords) > n {
+		headwords = headwords[:n]
+	}
+	return headwords
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A dictd dictionary in the FreeDict layout, offsets and sizes are single base64 digits
+func writeTestDictionary(t *testing.T, dir string) {
+	fiets := "fiets /fits/ <n, fem>\nbicycle\nbike\n"
+	fietsen := "fietsen /ˈfitsə(n)/ <v>\nto cycle\n"
+	digit := func(n int) string {
+		return string("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"[n])
+	}
+	index := "fiets\tA\t" + digit(len(fiets)) + "\nfietsen\t" + digit(len(fiets)) + "\t" + digit(len(fietsen)) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nld-eng.index"), []byte(index), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nld-eng.dict"), []byte(fiets+fietsen), 0o644))
+}
+
+func TestDictionaries(t *testing.T) {
+	dir := t.TempDir()
+	writeTestDictionary(t, dir)
+	dictionaries, err := loadDictionaries(dir)
+	require.NoError(t, err)
+	require.Len(t, dictionaries, 1)
+
+	dutch := List{SourceLanguage: "nl", TargetLanguage: "en"}
+	german := List{SourceLanguage: "de", TargetLanguage: "en"}
+	assert.Len(t, dictionaries.forList(dutch), 1)
+	assert.Len(t, dictionaries.forList(List{SourceLanguage: "nl-BE", TargetLanguage: "en-GB"}), 1, "regions don't matter")
+	assert.Empty(t, dictionaries.forList(german))
+
+	entry, ok := dictionaries.lookup(dutch, "Fiets")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"bicycle", "bike"}, entry.Translations)
+	_, ok = dictionaries.lookup(german, "fiets")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"fiets", "fietsen"}, dictionaries.complete(dutch, "fi", 10))
+	assert.Equal(t, []string{"fiets"}, dictionaries.complete(dutch, "fi", 1))
+
+	none, err := loadDictionaries(filepath.Join(dir, "missing"))
+	assert.NoError(t, err, "no directory means no dictionaries")
+	assert.Empty(t, none)
+}
+
+func TestSuggestFromDictionary(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	dir := t.TempDir()
+	writeTestDictionary(t, dir)
+	c.dictionaries, err = loadDictionaries(dir)
+	require.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+
+	get := func(query, trigger string) string {
+		req, _ := http.NewRequest("GET", "/suggest?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		if trigger != "" {
+			req.Header.Set("HX-Trigger-Name", trigger)
+		}
+		rr := httptest.NewRecorder()
+		appHandler(c.suggest).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := get("woord=fi", "woord")
+	assert.Contains(t, body, `<option value="fiets">`)
+	assert.Contains(t, body, `<option value="fietsen">`)
+
+	body = get("woord=fiets", "woord")
+	assert.Contains(t, body, `id="vertaling" name="vertaling" lang="en" placeholder="vertaling (English)" autocomplete="off" value="bicycle; bike"`)
+	assert.Contains(t, body, `value="fits"`)
+	assert.Contains(t, body, `id="woordsoort" name="woordsoort" placeholder="woordsoort" autocomplete="off" value="n"`)
+	assert.Contains(t, body, `value="fietsen"`, "the dictionary says it is a noun, so the plural is suggested")
+
+	body = get("woord=fiets&vertaling=bike&suggested_vertaling=", "woord")
+	assert.Contains(t, body, `autocomplete="off" value="bike"`, "what the user typed isn't overwritten")
+
+	body = get("woord=fiets&woordsoort=zn", "woordsoort")
+	assert.NotContains(t, body, `id="woordsoort"`, "the field being typed in isn't swapped")
+
+	body = get("woord=fietsen", "woord")
+	assert.Contains(t, body, `name="vervoeging"`, "the dictionary says it is a verb")
+}
@@ -0,0 +1,76 @@
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dictd writes offsets and sizes in base 64 with the digits in this order
+const dictdDigits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Reads the .index and .dict(.dz) files of a dictd dictionary
+func openDictd(indexPath string) (*Dictionary, error) {
+	f, err := os.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// dictd definitions are plain text, like the StarDict type m
+	d := &Dictionary{sameType: "m"}
+	var name *indexEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: want headword, offset and size separated by tabs", line)
+		}
+		offset, err := decodeDictdNumber(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		size, err := decodeDictdNumber(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		headword := fields[0]
+		// Entries like 00-database-short describe the dictionary itself
+		if strings.HasPrefix(headword, "00-database-") || strings.HasPrefix(headword, "00database") {
+			if headword == "00-database-short" || headword == "00databaseshort" {
+				name = &indexEntry{offset: offset, size: size}
+			}
+			continue
+		}
+		d.entries = append(d.entries, indexEntry{key: strings.ToLower(headword), headword: headword, offset: offset, size: size})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	d.data, err = readDict(strings.TrimSuffix(indexPath, ".index"))
+	if err != nil {
+		return nil, err
+	}
+	// The short name is the last line of the 00-database-short definition, the first repeats the headword
+	if name != nil {
+		if definition, ok := d.definition(*name); ok {
+			lines := strings.Split(strings.TrimSpace(definition), "\n")
+			d.Name = strings.TrimSpace(lines[len(lines)-1])
+		}
+	}
+	return d, nil
+}
+
+func decodeDictdNumber(s string) (int, error) {
+	n := 0
+	for _, r := range s {
+		digit := strings.IndexRune(dictdDigits, r)
+		if digit < 0 {
+			return 0, fmt.Errorf("%q is not a dictd number", s)
+		}
+		n = n*64 + digit
+	}
+	return n, nil
+}
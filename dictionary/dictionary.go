@@ -0,0 +1,219 @@
+// Package dictionary reads offline dictionaries in the StarDict and dictd formats, like the ones FreeDict publishes.
+// A dictionary is read into memory when it is opened, lookups don't touch the disk.
+package dictionary
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Entry is what a dictionary says about a headword, taken apart as far as the plain text allows
+type Entry struct {
+	Headword      string
+	Pronunciation string // IPA from the first line of the definition, without slashes
+	PartOfSpeech  string // the first abbreviation between angle brackets: n, v, adj
+	Translations  []string
+}
+
+// Dictionary is a sorted index of headwords with their definitions
+type Dictionary struct {
+	Name string
+	// ISO 639 codes from a file name like nld-eng, empty when the name doesn't say
+	From, To string
+
+	entries []indexEntry // sorted by key
+	data    []byte
+	// StarDict keeps the type of a definition in sametypesequence or in front of every definition
+	sameType string
+}
+
+type indexEntry struct {
+	key      string // lowercase headword
+	headword string
+	offset   int
+	size     int
+}
+
+// Opens the dictionary that path belongs to: the .ifo file of a StarDict dictionary or the .index file of a dictd one
+func Open(path string) (*Dictionary, error) {
+	var d *Dictionary
+	var err error
+	switch {
+	case strings.HasSuffix(path, ".ifo"):
+		d, err = openStarDict(path)
+	case strings.HasSuffix(path, ".index"):
+		d, err = openDictd(path)
+	default:
+		return nil, fmt.Errorf("%s is not a StarDict .ifo or dictd .index file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening dictionary %s: %w", path, err)
+	}
+	if d.Name == "" {
+		d.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	d.From, d.To = languagesFromName(filepath.Base(path))
+	sort.SliceStable(d.entries, func(i, j int) bool { return d.entries[i].key < d.entries[j].key })
+	return d, nil
+}
+
+// Opens every dictionary in dir. One that can't be read is logged and left out, the others are still worth having.
+func OpenDir(dir string) ([]*Dictionary, error) {
+	var paths []string
+	for _, pattern := range []string{"*.ifo", "*.index"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var dictionaries []*Dictionary
+	for _, path := range paths {
+		d, err := Open(path)
+		if err != nil {
+			log.Printf("Skipping dictionary: %v", err)
+			continue
+		}
+		dictionaries = append(dictionaries, d)
+	}
+	return dictionaries, nil
+}
+
+var languagePair = regexp.MustCompile(`^([a-z]{2,3})-([a-z]{2,3})\b`)
+
+// FreeDict names its files after the languages: nld-eng.index, deu-eng.ifo
+func languagesFromName(name string) (from, to string) {
+	if m := languagePair.FindStringSubmatch(name); m != nil {
+		return m[1], m[2]
+	}
+	return "", ""
+}
+
+// Reads a .dict file, or its dictzip-compressed .dict.dz, which is gzip with extra headers for random access
+func readDict(base string) ([]byte, error) {
+	if data, err := os.ReadFile(base + ".dict"); err == nil {
+		return data, nil
+	}
+	f, err := os.Open(base + ".dict.dz")
+	if err != nil {
+		return nil, fmt.Errorf("no .dict or .dict.dz next to the index: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// Looks up a headword ignoring case, a word can have more than one entry
+func (d *Dictionary) Lookup(word string) []Entry {
+	key := strings.ToLower(word)
+	i := sort.Search(len(d.entries), func(i int) bool { return d.entries[i].key >= key })
+	var entries []Entry
+	for ; i < len(d.entries) && d.entries[i].key == key; i++ {
+		if definition, ok := d.definition(d.entries[i]); ok {
+			entries = append(entries, parseDefinition(d.entries[i].headword, definition))
+		}
+	}
+	return entries
+}
+
+// Up to n headwords that start with prefix, ignoring case, in alphabetical order
+func (d *Dictionary) Complete(prefix string, n int) []string {
+	key := strings.ToLower(prefix)
+	if key == "" {
+		return nil
+	}
+	i := sort.Search(len(d.entries), func(i int) bool { return d.entries[i].key >= key })
+	var headwords []string
+	for ; i < len(d.entries) && len(headwords) < n && strings.HasPrefix(d.entries[i].key, key); i++ {
+		if len(headwords) == 0 || headwords[len(headwords)-1] != d.entries[i].headword {
+			headwords = append(headwords, d.entries[i].headword)
+		}
+	}
+	return headwords
+}
+
+// Number of headwords
+func (d *Dictionary) Len() int {
+	return len(d.entries)
+}
+
+func (d *Dictionary) definition(e indexEntry) (string, bool) {
+	// Compared this way round, a huge offset and size can't overflow into a small sum
+	if e.offset < 0 || e.size < 0 || e.offset > len(d.data) || e.size > len(d.data)-e.offset {
+		return "", false
+	}
+	raw := string(d.data[e.offset : e.offset+e.size])
+	if d.sameType == "" && raw != "" {
+		// Without sametypesequence every definition starts with its type and ends with a NUL
+		return plainText(raw[:1], strings.TrimRight(raw[1:], "\x00")), true
+	}
+	return plainText(d.sameType, raw), true
+}
+
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// Plain text of a definition of a StarDict type, HTML (h), XDXF (x) and Pango (g) definitions lose their markup
+func plainText(typ, definition string) string {
+	switch typ {
+	case "h", "x", "g":
+		definition = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n").Replace(definition)
+		definition = markup.ReplaceAllString(definition, "")
+		definition = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`).Replace(definition)
+	}
+	return definition
+}
+
+var (
+	pronunciation = regexp.MustCompile(`[/\[]([^/\]]+)[/\]]`)
+	partOfSpeech  = regexp.MustCompile(`<([^,>]+)[^>]*>`)
+	numbering     = regexp.MustCompile(`^\d+\.\s*`)
+)
+
+// Takes apart a definition in the FreeDict layout: the headword with its IPA and part of speech on the
+// first line, one translation per line after it. Definitions without the headword line are all translations.
+func parseDefinition(headword, definition string) Entry {
+	entry := Entry{Headword: headword}
+	lines := strings.Split(strings.TrimSpace(definition), "\n")
+	if rest, ok := cutPrefixFold(strings.TrimSpace(lines[0]), headword); ok {
+		if m := pronunciation.FindStringSubmatch(rest); m != nil {
+			entry.Pronunciation = strings.TrimSpace(m[1])
+		}
+		if m := partOfSpeech.FindStringSubmatch(rest); m != nil {
+			entry.PartOfSpeech = strings.TrimSpace(m[1])
+		}
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		line = numbering.ReplaceAllString(strings.TrimSpace(line), "")
+		if line != "" {
+			entry.Translations = append(entry.Translations, line)
+		}
+	}
+	return entry
+}
+
+// Like strings.CutPrefix, but ignoring case. The prefix is compared rune by rune, as the case of a letter
+// can take a different number of bytes than the letter itself, like ẞ and ß.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	for _, want := range prefix {
+		got, size := utf8.DecodeRuneInString(s)
+		if size == 0 || !strings.EqualFold(string(got), string(want)) {
+			return "", false
+		}
+		s = s[size:]
+	}
+	return s, true
+}
@@ -0,0 +1,172 @@
+package dictionary
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var definitions = []struct {
+	headword, definition string
+}{
+	{"fiets", "fiets /fits/ <n, fem>\nbicycle\nbike\n"},
+	{"fietsen", "fietsen /ˈfitsə(n)/ <v>\n1. to cycle\n2. to bike\n"},
+	{"huis", "house"},
+}
+
+func encodeDictd(n int) string {
+	if n == 0 {
+		return "A"
+	}
+	var digits []byte
+	for ; n > 0; n /= 64 {
+		digits = append([]byte{dictdDigits[n%64]}, digits...)
+	}
+	return string(digits)
+}
+
+// Writes a dictd dictionary with a compressed .dict.dz like FreeDict ships
+func writeDictd(t *testing.T, dir, name string) string {
+	var data bytes.Buffer
+	var index strings.Builder
+	entries := append([]struct{ headword, definition string }{{"00-database-short", "00-database-short\n     Nederlands-English FreeDict Dictionary\n"}}, definitions...)
+	for _, e := range entries {
+		fmt.Fprintf(&index, "%s\t%s\t%s\n", e.headword, encodeDictd(data.Len()), encodeDictd(len(e.definition)))
+		data.WriteString(e.definition)
+	}
+
+	path := filepath.Join(dir, name+".index")
+	require.NoError(t, os.WriteFile(path, []byte(index.String()), 0o644))
+	var dz bytes.Buffer
+	zw := gzip.NewWriter(&dz)
+	zw.Write(data.Bytes())
+	zw.Close()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".dict.dz"), dz.Bytes(), 0o644))
+	return path
+}
+
+// Writes a StarDict dictionary, with sametypesequence or with a type in front of every definition
+func writeStarDict(t *testing.T, dir, name, sameType string) string {
+	var data, idx bytes.Buffer
+	for _, e := range definitions {
+		definition := e.definition
+		if sameType == "" {
+			definition = "m" + definition + "\x00"
+		}
+		idx.WriteString(e.headword + "\x00")
+		binary.Write(&idx, binary.BigEndian, uint32(data.Len()))
+		binary.Write(&idx, binary.BigEndian, uint32(len(definition)))
+		data.WriteString(definition)
+	}
+
+	ifo := fmt.Sprintf("StarDict's dict ifo file\nversion=2.4.2\nbookname=Test StarDict\nwordcount=%d\nidxfilesize=%d\n", len(definitions), idx.Len())
+	if sameType != "" {
+		ifo += "sametypesequence=" + sameType + "\n"
+	}
+	path := filepath.Join(dir, name+".ifo")
+	require.NoError(t, os.WriteFile(path, []byte(ifo), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".idx"), idx.Bytes(), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".dict"), data.Bytes(), 0o644))
+	return path
+}
+
+func TestFormats(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{
+		"dictd":                          writeDictd(t, dir, "nld-eng"),
+		"StarDict with sametypesequence": writeStarDict(t, dir, "nld-eng-stardict", "m"),
+		"StarDict with types":            writeStarDict(t, dir, "typed", ""),
+	}
+
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			d, err := Open(path)
+			require.NoError(t, err)
+			assert.Equal(t, 3, d.Len())
+
+			assert.Equal(t, []Entry{{Headword: "fiets", Pronunciation: "fits", PartOfSpeech: "n", Translations: []string{"bicycle", "bike"}}}, d.Lookup("Fiets"))
+			assert.Equal(t, []Entry{{Headword: "fietsen", Pronunciation: "ˈfitsə(n)", PartOfSpeech: "v", Translations: []string{"to cycle", "to bike"}}}, d.Lookup("fietsen"))
+			assert.Equal(t, []Entry{{Headword: "huis", Translations: []string{"house"}}}, d.Lookup("huis"))
+			assert.Empty(t, d.Lookup("auto"))
+
+			assert.Equal(t, []string{"fiets", "fietsen"}, d.Complete("fie", 10))
+			assert.Equal(t, []string{"fiets"}, d.Complete("FIE", 1))
+			assert.Empty(t, d.Complete("", 10))
+		})
+	}
+
+	d, err := Open(paths["dictd"])
+	require.NoError(t, err)
+	assert.Equal(t, "Nederlands-English FreeDict Dictionary", d.Name)
+	assert.Equal(t, "nld", d.From)
+	assert.Equal(t, "eng", d.To)
+
+	d, err = Open(paths["StarDict with types"])
+	require.NoError(t, err)
+	assert.Equal(t, "Test StarDict", d.Name)
+	assert.Empty(t, d.From, "the name doesn't say")
+
+	all, err := OpenDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.index")
+	os.WriteFile(path, []byte("fiets\tA\n"), 0o644)
+	_, err := Open(path)
+	assert.Error(t, err, "a line without a size")
+
+	os.WriteFile(path, []byte("fiets\tA\tB\n"), 0o644)
+	_, err = Open(path)
+	assert.Error(t, err, "no .dict next to it")
+
+	path = filepath.Join(dir, "broken.ifo")
+	os.WriteFile(path, []byte("not an ifo\n"), 0o644)
+	_, err = Open(path)
+	assert.Error(t, err)
+
+	_, err = Open(filepath.Join(dir, "words.txt"))
+	assert.Error(t, err)
+
+	all, err := OpenDir(dir)
+	assert.NoError(t, err, "broken dictionaries are skipped")
+	assert.Empty(t, all)
+
+	// 2^62 as offset and size, their sum overflows
+	path = filepath.Join(dir, "huge.index")
+	os.WriteFile(path, []byte("fiets\tEAAAAAAAAAA\tEAAAAAAAAAA\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "huge.dict"), []byte("bicycle"), 0o644)
+	d, err := Open(path)
+	require.NoError(t, err)
+	assert.Empty(t, d.Lookup("fiets"), "a definition outside the .dict file")
+}
+
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, "bicycle\nbike", plainText("h", "<b>bicycle</b><br>bike"))
+	assert.Equal(t, "<n>", plainText("m", "<n>"))
+}
+
+func TestParseDefinition(t *testing.T) {
+	entry := parseDefinition("fiets", "Fiets /fits/ <n>\nbicycle")
+	assert.Equal(t, Entry{Headword: "fiets", Pronunciation: "fits", PartOfSpeech: "n", Translations: []string{"bicycle"}}, entry)
+
+	// İ is two bytes, but lowercases to three
+	entry = parseDefinition("İstanbul", "istanbul /isˈtanbul/ <prop>\nIstanbul")
+	assert.Equal(t, Entry{Headword: "İstanbul", Translations: []string{"istanbul /isˈtanbul/ <prop>", "Istanbul"}}, entry)
+	entry = parseDefinition("İstanbul", "İSTANBUL /isˈtanbul/ <prop>\nIstanbul")
+	assert.Equal(t, Entry{Headword: "İstanbul", Pronunciation: "isˈtanbul", PartOfSpeech: "prop", Translations: []string{"Istanbul"}}, entry)
+	assert.NotPanics(t, func() { parseDefinition("İ", "i\nI") })
+
+	entry = parseDefinition("ẞ", "ß <n>\nsharp s")
+	assert.Equal(t, Entry{Headword: "ẞ", PartOfSpeech: "n", Translations: []string{"sharp s"}}, entry)
+}
@@ -0,0 +1,89 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Reads the .ifo, .idx and .dict(.dz) files of a StarDict dictionary
+func openStarDict(ifoPath string) (*Dictionary, error) {
+	info, err := readIfo(ifoPath)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(ifoPath, ".ifo")
+	idx, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		return nil, err
+	}
+	offsetSize := 4
+	if info["idxoffsetbits"] == "64" {
+		offsetSize = 8
+	}
+	entries, err := parseIdx(idx, offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := strconv.Atoi(info["wordcount"]); err == nil && count != len(entries) {
+		return nil, fmt.Errorf("the .ifo says %d words, the .idx has %d", count, len(entries))
+	}
+	data, err := readDict(base)
+	if err != nil {
+		return nil, err
+	}
+	// Only the first type is used when a definition has more than one
+	sameType := info["sametypesequence"]
+	if sameType != "" {
+		sameType = sameType[:1]
+	}
+	return &Dictionary{Name: info["bookname"], entries: entries, data: data, sameType: sameType}, nil
+}
+
+// The key=value lines after the magic first line
+func readIfo(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || scanner.Text() != "StarDict's dict ifo file" {
+		return nil, fmt.Errorf("%s is not a StarDict .ifo file", path)
+	}
+	info := map[string]string{}
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			info[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return info, scanner.Err()
+}
+
+// Every .idx entry is a NUL-terminated headword followed by the big-endian offset and size of its definition
+func parseIdx(idx []byte, offsetSize int) ([]indexEntry, error) {
+	var entries []indexEntry
+	for len(idx) > 0 {
+		end := bytes.IndexByte(idx, 0)
+		if end < 0 || len(idx) < end+1+offsetSize+4 {
+			return nil, fmt.Errorf("truncated .idx entry after %d words", len(entries))
+		}
+		headword := string(idx[:end])
+		idx = idx[end+1:]
+		var offset int
+		if offsetSize == 8 {
+			offset = int(binary.BigEndian.Uint64(idx))
+		} else {
+			offset = int(binary.BigEndian.Uint32(idx))
+		}
+		size := int(binary.BigEndian.Uint32(idx[offsetSize:]))
+		idx = idx[offsetSize+4:]
+		entries = append(entries, indexEntry{key: strings.ToLower(headword), headword: headword, offset: offset, size: size})
+	}
+	return entries, nil
+}
@@ -0,0 +1,76 @@
+// Package dutch guesses the predictable forms of Dutch words: the plural and diminutive of a noun
+// and the conjugation of a weak verb. It knows the spelling rules taught at school and nothing about
+// irregular words, so "dag" gets "daggen" and "lopen" gets "loopte". Offer the results as suggestions only.
+package dutch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Splits a word around its last vowel: "maan" is "m", "aa", "n" and "auto" is "aut", "o", "".
+// "ij" counts as a vowel.
+func splitEnd(s string) (head, vowel, tail string) {
+	r := []rune(s)
+	i := len(r)
+	for i > 0 && !isVowelAt(r, i-1) {
+		i--
+	}
+	j := i
+	for j > 0 && isVowelAt(r, j-1) {
+		j--
+	}
+	return string(r[:j]), string(r[j:i]), string(r[i:])
+}
+
+func isVowelAt(r []rune, i int) bool {
+	switch unicode.ToLower(r[i]) {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'é', 'è', 'ê', 'ë', 'ï', 'ö', 'ü':
+		return true
+	case 'j':
+		return i > 0 && unicode.ToLower(r[i-1]) == 'i'
+	}
+	return false
+}
+
+// Counts groups of vowels, a trema starts a new syllable: "zeeën" has two
+func syllables(s string) int {
+	r := []rune(s)
+	count := 0
+	for i := range r {
+		if !isVowelAt(r, i) {
+			continue
+		}
+		if i == 0 || !isVowelAt(r, i-1) || strings.ContainsRune("ëïöü", unicode.ToLower(r[i])) {
+			count++
+		}
+	}
+	return count
+}
+
+// aa, ee, oo, uu: a long vowel that is written with one letter in an open syllable
+func isDoubled(vowel string) bool {
+	return len(vowel) == 2 && vowel[0] == vowel[1] && strings.Contains("aeou", vowel[:1])
+}
+
+// f and s become v and z between vowels: brief, brieven; huis, huizen
+func voiced(consonant string) string {
+	switch consonant {
+	case "f":
+		return "v"
+	case "s":
+		return "z"
+	}
+	return consonant
+}
+
+// And back at the end of a verb stem: leven, leef; reizen, reis
+func unvoiced(consonant string) string {
+	switch consonant {
+	case "v":
+		return "f"
+	case "z":
+		return "s"
+	}
+	return consonant
+}
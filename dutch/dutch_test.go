@@ -0,0 +1,180 @@
+package dutch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		noun, plural string
+	}{
+		{"boek", "boeken"},
+		{"hond", "honden"},
+		{"kat", "katten"},
+		{"bed", "bedden"},
+		{"bus", "bussen"},
+		{"maan", "manen"},
+		{"boom", "bomen"},
+		{"muur", "muren"},
+		{"probleem", "problemen"},
+		{"kaas", "kazen"},
+		{"huis", "huizen"},
+		{"brief", "brieven"},
+		{"wolf", "wolven"},
+		{"trein", "treinen"},
+		{"vrouw", "vrouwen"},
+		{"tafel", "tafels"},
+		{"bezem", "bezems"},
+		{"keuken", "keukens"},
+		{"kamer", "kamers"},
+		{"tante", "tantes"},
+		{"huisje", "huisjes"},
+		{"vakantie", "vakanties"},
+		{"auto", "auto's"},
+		{"menu", "menu's"},
+		{"taxi", "taxi's"},
+		{"zee", "zeeën"},
+		{"idee", "ideeën"},
+		{"knie", "knieën"},
+		{"bij", "bijen"},
+		{"vrijheid", "vrijheden"},
+		{"", ""},
+		// Lowercasing changes the number of bytes of these letters
+		{"Ⱥx", "ⱥxen"},
+		{"xȺ", "xⱥen"},
+		{"Ⱦ", "ⱦen"},
+		{"tȾ", "tⱦen"},
+		{"İx", "ixen"},
+		{"Ⱥring", "Ⱥringen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.noun, func(t *testing.T) {
+			assert.Equal(t, tt.plural, Plural(tt.noun))
+		})
+	}
+}
+
+func TestDiminutive(t *testing.T) {
+	tests := []struct {
+		noun, diminutive string
+	}{
+		{"huis", "huisje"},
+		{"boek", "boekje"},
+		{"kat", "katje"},
+		{"hond", "hondje"},
+		{"stoel", "stoeltje"},
+		{"deur", "deurtje"},
+		{"maan", "maantje"},
+		{"tafel", "tafeltje"},
+		{"keuken", "keukentje"},
+		{"vrouw", "vrouwtje"},
+		{"bal", "balletje"},
+		{"man", "mannetje"},
+		{"ster", "sterretje"},
+		{"kam", "kammetje"},
+		{"boom", "boompje"},
+		{"arm", "armpje"},
+		{"bezem", "bezempje"},
+		{"ring", "ringetje"},
+		{"koning", "koninkje"},
+		{"Ⱥkoning", "Ⱥkoninkje"},
+		{"oma", "omaatje"},
+		{"auto", "autootje"},
+		{"menu", "menuutje"},
+		{"taxi", "taxi'tje"},
+		{"zee", "zeetje"},
+		{"knie", "knietje"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.noun, func(t *testing.T) {
+			assert.Equal(t, tt.diminutive, Diminutive(tt.noun))
+		})
+	}
+}
+
+func TestConjugateWeak(t *testing.T) {
+	tests := []struct {
+		infinitive string
+		want       Conjugation
+	}{
+		{"werken", Conjugation{"werk", "werkt", "werkt", "werken", "werkte", "werkten", "gewerkt"}},
+		{"maken", Conjugation{"maak", "maakt", "maakt", "maken", "maakte", "maakten", "gemaakt"}},
+		{"horen", Conjugation{"hoor", "hoort", "hoort", "horen", "hoorde", "hoorden", "gehoord"}},
+		{"leven", Conjugation{"leef", "leeft", "leeft", "leven", "leefde", "leefden", "geleefd"}},
+		{"reizen", Conjugation{"reis", "reist", "reist", "reizen", "reisde", "reisden", "gereisd"}},
+		{"zetten", Conjugation{"zet", "zet", "zet", "zetten", "zette", "zetten", "gezet"}},
+		{"stoppen", Conjugation{"stop", "stopt", "stopt", "stoppen", "stopte", "stopten", "gestopt"}},
+		{"fietsen", Conjugation{"fiets", "fietst", "fietst", "fietsen", "fietste", "fietsten", "gefietst"}},
+		{"lachen", Conjugation{"lach", "lacht", "lacht", "lachen", "lachte", "lachten", "gelacht"}},
+		{"praten", Conjugation{"praat", "praat", "praat", "praten", "praatte", "praatten", "gepraat"}},
+		{"branden", Conjugation{"brand", "brandt", "brandt", "branden", "brandde", "brandden", "gebrand"}},
+		{"antwoorden", Conjugation{"antwoord", "antwoordt", "antwoordt", "antwoorden", "antwoordde", "antwoordden", "geantwoord"}},
+		{"betalen", Conjugation{"betaal", "betaalt", "betaalt", "betalen", "betaalde", "betaalden", "betaald"}},
+		{"vertellen", Conjugation{"vertel", "vertelt", "vertelt", "vertellen", "vertelde", "vertelden", "verteld"}},
+		{"verhuizen", Conjugation{"verhuis", "verhuist", "verhuist", "verhuizen", "verhuisde", "verhuisden", "verhuisd"}},
+		{"wandelen", Conjugation{"wandel", "wandelt", "wandelt", "wandelen", "wandelde", "wandelden", "gewandeld"}},
+		{"openen", Conjugation{"open", "opent", "opent", "openen", "opende", "openden", "geopend"}},
+		{"luisteren", Conjugation{"luister", "luistert", "luistert", "luisteren", "luisterde", "luisterden", "geluisterd"}},
+		{"studeren", Conjugation{"studeer", "studeert", "studeert", "studeren", "studeerde", "studeerden", "gestudeerd"}},
+		{"eindigen", Conjugation{"eindig", "eindigt", "eindigt", "eindigen", "eindigde", "eindigden", "geëindigd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.infinitive, func(t *testing.T) {
+			got, ok := ConjugateWeak(tt.infinitive)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, word := range []string{"gaan", "zien", "doen", "huis", ""} {
+		_, ok := ConjugateWeak(word)
+		assert.False(t, ok, word)
+	}
+}
+
+func TestLemmas(t *testing.T) {
+	tests := []struct {
+		form, lemma string
+	}{
+		{"boeken", "boek"},
+		{"katten", "kat"},
+		{"manen", "maan"},
+		{"kazen", "kaas"},
+		{"huizen", "huis"},
+		{"brieven", "brief"},
+		{"wolven", "wolf"},
+		{"tafels", "tafel"},
+		{"auto's", "auto"},
+		{"zeeën", "zee"},
+		{"mogelijkheden", "mogelijkheid"},
+		{"huisje", "huis"},
+		{"huisjes", "huis"},
+		{"balletje", "bal"},
+		{"kammetje", "kam"},
+		{"boompje", "boom"},
+		{"koninkje", "koning"},
+		{"autootje", "auto"},
+		{"ringetje", "ring"},
+		{"werkte", "werken"},
+		{"werkten", "werken"},
+		{"gewerkt", "werken"},
+		{"maakt", "maken"},
+		{"leefde", "leven"},
+		{"reisde", "reizen"},
+		{"zet", "zetten"},
+		{"betaald", "betalen"},
+		{"geërfd", "erven"},
+	}
+	for _, test := range tests {
+		assert.Contains(t, Lemmas(test.form), test.lemma, test.form)
+	}
+
+	assert.NotContains(t, Lemmas("boeken"), "boeken", "a word isn't its own lemma")
+	assert.NotContains(t, Lemmas("liep"), "lopen", "irregular forms aren't found")
+}
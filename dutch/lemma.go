@@ -0,0 +1,149 @@
+package dutch
+
+import (
+	"slices"
+	"strings"
+)
+
+// Lemmas guesses which words word is a form of by undoing the rules of this package: the singular of a
+// plural or diminutive and the infinitive of a weak verb form. Every guess is checked by making the form
+// again from it, but "huis" still gets "hui" because that would be its plural. Irregular forms aren't found.
+func Lemmas(word string) []string {
+	w := strings.ToLower(strings.TrimSpace(word))
+	var lemmas []string
+	add := func(lemma string) {
+		if lemma != w && !slices.Contains(lemmas, lemma) {
+			lemmas = append(lemmas, lemma)
+		}
+	}
+
+	for _, noun := range nounCandidates(w) {
+		diminutive := Diminutive(noun)
+		if Plural(noun) == w || diminutive == w || diminutive+"s" == w {
+			add(noun)
+		}
+	}
+	for _, infinitive := range infinitiveCandidates(w) {
+		c, ok := ConjugateWeak(infinitive)
+		if ok && slices.Contains([]string{c.Ik, c.Hij, c.VerledenEnkelvoud, c.VerledenMeervoud, c.VoltooidDeelwoord}, w) {
+			add(infinitive)
+		}
+	}
+	return lemmas
+}
+
+// Endings of plurals and diminutives, longest first so "tjes" is tried before "s"
+var nounEndings = []string{"metjes", "etjes", "'tjes", "tjes", "pjes", "jes", "metje", "etje", "'tje", "tje", "pje", "je", "ën", "en", "'s", "s"}
+
+func nounCandidates(w string) []string {
+	var candidates []string
+	if strings.HasSuffix(w, "heden") {
+		candidates = append(candidates, strings.TrimSuffix(w, "heden")+"heid")
+	}
+	for _, ending := range nounEndings {
+		if base, ok := strings.CutSuffix(w, ending); ok && base != "" {
+			candidates = append(candidates, variants(base, undoubleConsonant, undoubleVowel, unvoiceEnd, doubleVowel, koning)...)
+		}
+	}
+	return candidates
+}
+
+// Endings of weak verb forms after the stem, and the prefixes of past participles
+var (
+	verbEndings    = []string{"ten", "den", "te", "de", "t", "d", ""}
+	verbPrefixes   = []string{"geë", "geï", "ge", ""}
+	prefixReplaces = map[string]string{"geë": "e", "geï": "i"}
+)
+
+func infinitiveCandidates(w string) []string {
+	var candidates []string
+	for _, prefix := range verbPrefixes {
+		rest, ok := strings.CutPrefix(w, prefix)
+		if !ok {
+			continue
+		}
+		rest = prefixReplaces[prefix] + rest
+		for _, ending := range verbEndings {
+			if stem, ok := strings.CutSuffix(rest, ending); ok && stem != "" {
+				for _, v := range variants(stem, undoubleVowel, voiceEnd, doubleConsonant) {
+					candidates = append(candidates, v+"en")
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// The word with every combination of the changes that apply to it, in the order they are given
+func variants(s string, changes ...func(string) (string, bool)) []string {
+	all := []string{s}
+	for _, change := range changes {
+		for _, v := range all {
+			if changed, ok := change(v); ok && !slices.Contains(all, changed) {
+				all = append(all, changed)
+			}
+		}
+	}
+	return all
+}
+
+// katt → kat
+func undoubleConsonant(s string) (string, bool) {
+	r := []rune(s)
+	n := len(r)
+	if n >= 2 && r[n-1] == r[n-2] && !isVowelAt(r, n-1) {
+		return string(r[:n-1]), true
+	}
+	return s, false
+}
+
+// maak → mak, autoo → auto
+func undoubleVowel(s string) (string, bool) {
+	head, vowel, tail := splitEnd(s)
+	if isDoubled(vowel) {
+		return head + vowel[:1] + tail, true
+	}
+	return s, false
+}
+
+// man → maan, the open syllable of manen
+func doubleVowel(s string) (string, bool) {
+	head, vowel, tail := splitEnd(s)
+	if len(vowel) == 1 && strings.Contains("aeou", vowel) && len([]rune(tail)) == 1 {
+		return head + vowel + vowel + tail, true
+	}
+	return s, false
+}
+
+// kaz → kas, briev → brief
+func unvoiceEnd(s string) (string, bool) {
+	if last := s[len(s)-1:]; unvoiced(last) != last {
+		return s[:len(s)-1] + unvoiced(last), true
+	}
+	return s, false
+}
+
+// leef → leev, reis → reiz, for the infinitive
+func voiceEnd(s string) (string, bool) {
+	if last := s[len(s)-1:]; voiced(last) != last {
+		return s[:len(s)-1] + voiced(last), true
+	}
+	return s, false
+}
+
+// zet → zett
+func doubleConsonant(s string) (string, bool) {
+	r := []rune(s)
+	if n := len(r); n >= 2 && !isVowelAt(r, n-1) && isVowelAt(r, n-2) {
+		return s + string(r[n-1]), true
+	}
+	return s, false
+}
+
+// konink → koning, the diminutive of -ing words
+func koning(s string) (string, bool) {
+	if base, ok := strings.CutSuffix(s, "nk"); ok {
+		return base + "ng", true
+	}
+	return s, false
+}
@@ -0,0 +1,105 @@
+package dutch
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Plural suggests the plural of a noun
+func Plural(noun string) string {
+	s := strings.TrimSpace(noun)
+	if s == "" {
+		return ""
+	}
+	lower := strings.ToLower(s)
+	head, vowel, tail := splitEnd(lower)
+
+	switch {
+	case strings.HasSuffix(lower, "heid"):
+		return prefix(s, lower, len(lower)-len("heid")) + "heden"
+	case tail == "":
+		return s + vowelPluralEnding(lower, vowel)
+	case syllables(lower) > 1 && vowel == "e" && strings.Contains("lmnr", tail):
+		// Unstressed -el, -em, -en, -er: tafels, bezems, keukens, kamers
+		return s + "s"
+	}
+	return prefix(s, lower, len(head)) + openSyllable(vowel, tail) + "en"
+}
+
+func vowelPluralEnding(word, vowel string) string {
+	switch {
+	case vowel == "ee":
+		return "ën" // zeeën, ideeën
+	case vowel == "ie" && syllables(word) == 1:
+		return "ën" // knieën
+	case vowel == "ij":
+		return "en" // bijen
+	case len(vowel) == 1 && vowel != "e":
+		return "'s" // auto's, menu's, taxi's
+	}
+	return "s" // tantes, huisjes, vakanties, cadeaus
+}
+
+// The first n bytes of lower, lowercased s, in the case of s. Lowercasing can change the number of bytes
+// of a letter, like Ⱥ, so s is cut after as many letters instead.
+func prefix(s, lower string, n int) string {
+	letters := utf8.RuneCountInString(lower[:n])
+	for i := range s {
+		if letters == 0 {
+			return s[:i]
+		}
+		letters--
+	}
+	return s
+}
+
+// What "en" is added to after the last vowel of a noun: maan, manen; bal, ballen; huis, huizen
+func openSyllable(vowel, tail string) string {
+	switch {
+	case len(tail) == 1 && isDoubled(vowel):
+		return vowel[:1] + voiced(tail)
+	case len(tail) == 1 && len(vowel) == 1 && !strings.Contains("hjwxy", tail):
+		return vowel + tail + tail
+	case len(tail) == 1:
+		return vowel + voiced(tail)
+	case tail == "lf" || tail == "rf":
+		return vowel + tail[:1] + "v" // wolven
+	}
+	return vowel + tail
+}
+
+// Diminutive suggests the diminutive of a noun
+func Diminutive(noun string) string {
+	s := strings.TrimSpace(noun)
+	if s == "" {
+		return ""
+	}
+	lower := strings.ToLower(s)
+	_, vowel, tail := splitEnd(lower)
+	// A short stressed vowel doubles the consonant after it; the e of tafel and bezem isn't stressed
+	short := len(vowel) == 1 && !(syllables(lower) > 1 && vowel == "e")
+
+	switch {
+	case tail == "":
+		switch vowel {
+		case "a", "o", "u":
+			return s + vowel + "tje" // omaatje, autootje, menuutje
+		case "i", "y":
+			return s + "'tje" // taxi'tje
+		}
+		return s + "tje" // zeetje, knietje, tantetje
+	case strings.HasSuffix(lower, "ing") && syllables(lower) > 1:
+		return prefix(s, lower, len(lower)-len("ng")) + "nkje" // koninkje
+	case tail == "ng":
+		return s + "etje" // ringetje
+	case tail == "m" && short:
+		return s + "metje" // kammetje
+	case strings.HasSuffix(tail, "m"):
+		return s + "pje" // boompje, armpje, bezempje
+	case len(tail) == 1 && strings.Contains("lnr", tail) && short:
+		return s + tail + "etje" // balletje, mannetje, sterretje
+	case strings.ContainsAny(tail[len(tail)-1:], "lnrw"):
+		return s + "tje" // stoeltje, tafeltje, deurtje, vrouwtje
+	}
+	return s + "je"
+}
@@ -0,0 +1,94 @@
+package dutch
+
+import "strings"
+
+// Conjugation of a verb, named like the forms in the conjugation table of the app
+type Conjugation struct {
+	Ik                string
+	Jij               string
+	Hij               string
+	Meervoud          string
+	VerledenEnkelvoud string
+	VerledenMeervoud  string
+	VoltooidDeelwoord string
+}
+
+// Prefixes that are never stressed and take the place of ge- in the past participle: betalen, betaald
+var unstressedPrefixes = []string{"be", "ge", "her", "ver", "ont", "er"}
+
+// ConjugateWeak conjugates an infinitive as a weak verb. It returns false for things that don't look
+// like an infinitive, or are too short to be a weak verb, like gaan, zien and doen.
+func ConjugateWeak(infinitive string) (Conjugation, bool) {
+	inf := strings.ToLower(strings.TrimSpace(infinitive))
+	if !strings.HasSuffix(inf, "en") || syllables(inf) < 2 {
+		return Conjugation{}, false
+	}
+
+	head, vowel, tail := splitEnd(strings.TrimSuffix(inf, "en"))
+	if tail == "" {
+		return Conjugation{}, false
+	}
+
+	// 't kofschip: the sound the stem ends in before spelling changes decides between -te and -de,
+	// so leven gets leefde and reizen reisde
+	past, participleEnding := "de", "d"
+	if strings.HasSuffix(tail, "ch") || strings.ContainsAny(tail[len(tail)-1:], "tkfspx") {
+		past, participleEnding = "te", "t"
+	}
+
+	switch {
+	case len(tail) >= 2 && tail[len(tail)-1] == tail[len(tail)-2]:
+		tail = tail[:len(tail)-1] // zetten, zet
+	case len(tail) == 1 && len(vowel) == 1 && strings.Contains("aeou", vowel) && !unstressedE(head, vowel, tail):
+		vowel += vowel // maken, maak
+	}
+	tail = tail[:len(tail)-1] + unvoiced(tail[len(tail)-1:])
+	stem := head + vowel + tail
+
+	if strings.HasSuffix(stem, participleEnding) {
+		participleEnding = "" // gepraat, gebrand
+	}
+	hij := stem + "t"
+	if strings.HasSuffix(stem, "t") {
+		hij = stem
+	}
+
+	return Conjugation{
+		Ik:                stem,
+		Jij:               hij,
+		Hij:               hij,
+		Meervoud:          inf,
+		VerledenEnkelvoud: stem + past,
+		VerledenMeervoud:  stem + past + "n",
+		VoltooidDeelwoord: participle(inf, stem) + participleEnding,
+	}, true
+}
+
+// Whether the last e of a stem like wandel, open or luister isn't stressed and stays short.
+// -el and -en stems are almost always like that, -er stems only after two consonants (studeren is stressed).
+func unstressedE(head, vowel, tail string) bool {
+	if vowel != "e" || syllables(head) == 0 {
+		return false
+	}
+	if tail == "l" || tail == "n" {
+		return true
+	}
+	_, _, before := splitEnd(head)
+	return tail == "r" && len(before) >= 2
+}
+
+func participle(infinitive, stem string) string {
+	for _, prefix := range unstressedPrefixes {
+		// The rest has to be a verb of its own, so geven and beven don't count
+		if strings.HasPrefix(infinitive, prefix) && syllables(strings.TrimPrefix(infinitive, prefix)) >= 2 {
+			return stem
+		}
+	}
+	switch {
+	case strings.HasPrefix(stem, "e"):
+		return "geë" + strings.TrimPrefix(stem, "e")
+	case strings.HasPrefix(stem, "i"):
+		return "geï" + strings.TrimPrefix(stem, "i")
+	}
+	return "ge" + stem
+}
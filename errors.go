@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	responseNotAuthorised = `<p>You are not autorized. <a href="/login">Log in</a></p>`
+	responseInternalError = `<p>Something went wrong on our side, try again later</p>`
+)
+
+// HTTPError is an error that knows how it should be presented to the client.
+// Message is an HTML fragment shown to the user, Err is the underlying cause that only ends up in the logs.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, http.StatusText(e.Status), e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func newHTTPError(status int, message string, err error) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: err}
+}
+
+var errNotAuthorised = newHTTPError(http.StatusUnauthorized, responseNotAuthorised, nil)
+
+// appHandler is a handler that returns an error instead of writing the error response itself.
+// Errors are rendered in one place by ServeHTTP.
+type appHandler func(http.ResponseWriter, *http.Request) error
+
+func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		renderError(w, r, err)
+	}
+}
+
+// Writes the error as HTML, or as JSON when the client asked for it.
+// Anything that isn't an *HTTPError is treated as an internal server error and its details are hidden from the client.
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = newHTTPError(http.StatusInternalServerError, responseInternalError, err)
+	}
+
+	if httpErr.Status >= http.StatusInternalServerError || httpErr.Err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, httpErr)
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(httpErr.Status)
+		json.NewEncoder(w).Encode(struct {
+			Status  int    `json:"status"`
+			Error   string `json:"error"`
+			Message string `json:"message,omitempty"`
+		}{
+			Status:  httpErr.Status,
+			Error:   http.StatusText(httpErr.Status),
+			Message: stripTags(httpErr.Message),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(httpErr.Status)
+	w.Write([]byte(httpErr.Message))
+}
+
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Turns an HTML response fragment into plain text for JSON clients
+func stripTags(fragment string) string {
+	text := tagPattern.ReplaceAllString(fragment, "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderErrorHTML(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+
+	renderError(rr, req, newHTTPError(http.StatusBadRequest, "<p>Nope</p>", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "<p>Nope</p>", rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+}
+
+func TestRenderErrorJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+
+	renderError(rr, req, errNotAuthorised)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"status": 401, "error": "Unauthorized", "message": "You are not autorized. Log in"}`, rr.Body.String())
+}
+
+func TestRenderErrorHidesInternalErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+
+	renderError(rr, req, errors.New("database is on fire"))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "fire")
+}
+
+func TestAppHandlerAddUnauthorised(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	req := httptest.NewRequest("POST", "/add/", nil)
+	rr := httptest.NewRecorder()
+
+	appHandler(c.add).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
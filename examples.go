@@ -0,0 +1,274 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Longest example sentence and translation, in characters
+const maxExampleLength = 500
+
+var responseBadExample = `<p>An example needs a sentence of at most 500 characters</p>`
+var responseNoExamples = `<p>Er zijn nog geen voorbeeldzinnen in deze lijst om te oefenen.</p>`
+
+// Example is a sentence that shows a word in use
+type Example struct {
+	ID        int
+	Zin       string
+	Vertaling string
+}
+
+// Cloze is an example with one form of its word blanked out
+type Cloze struct {
+	Before string
+	Answer string // the blanked form as it is written in the sentence
+	After  string
+}
+
+// The forms of a word that are blanked in its examples: the word, its noun forms and its conjugation.
+// Dutch verbs without a saved conjugation use the suggested one, so "werkte" is blanked for werken.
+func clozeForms(word Word, list List) []string {
+	forms := []string{word.Woord}
+	for _, field := range []string{word.Meervoud, word.Verkleinwoord} {
+		// Merged fields hold more than one form
+		forms = append(forms, strings.Split(field, "; ")...)
+	}
+	conjugation := word.Vervoeging
+	if conjugation == nil && list.IsDutch() {
+		if suggested, ok := suggestConjugation(word.Woord); ok {
+			conjugation = &suggested
+		}
+	}
+	if conjugation != nil {
+		forms = append(forms, conjugation.forms()...)
+	}
+	return forms
+}
+
+// A span of letters in a sentence, apostrophes and hyphens inside a word are part of it
+type token struct {
+	text       string
+	start, end int // byte offsets in the sentence
+}
+
+func tokenize(sentence string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range sentence {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && (r == '\'' || r == '’' || r == '-'))
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, token{sentence[start:i], start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{sentence[start:], start, len(sentence)})
+	}
+	// A quote right after a word closes a quotation rather than belonging to the word
+	for i := range tokens {
+		trimmed := strings.TrimRight(tokens[i].text, "'’-")
+		tokens[i].end -= len(tokens[i].text) - len(trimmed)
+		tokens[i].text = trimmed
+	}
+	return tokens
+}
+
+// Blanks the first occurrence of any of the forms in the sentence, ignoring case.
+// Forms of more than one word ("sta op") only match when the words follow each other.
+func makeCloze(sentence string, forms []string) (Cloze, bool) {
+	tokens := tokenize(sentence)
+	var formTokens [][]token
+	for _, form := range forms {
+		if t := tokenize(form); len(t) > 0 {
+			formTokens = append(formTokens, t)
+		}
+	}
+
+	for i := range tokens {
+		longest := 0
+		for _, form := range formTokens {
+			if len(form) > longest && matchesAt(tokens[i:], form) {
+				longest = len(form)
+			}
+		}
+		if longest > 0 {
+			start, end := tokens[i].start, tokens[i+longest-1].end
+			return Cloze{Before: sentence[:start], Answer: sentence[start:end], After: sentence[end:]}, true
+		}
+	}
+	return Cloze{}, false
+}
+
+func matchesAt(tokens, form []token) bool {
+	if len(tokens) < len(form) {
+		return false
+	}
+	for i := range form {
+		if !strings.EqualFold(tokens[i].text, form[i].text) {
+			return false
+		}
+	}
+	return true
+}
+
+// Answers are checked ignoring case and extra spaces
+func (c Cloze) Check(answer string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(answer), " "), strings.Join(strings.Fields(c.Answer), " "))
+}
+
+// Sends the form for adding an example sentence to a saved word
+func (c *Context) exampleForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Woord string
+		List  List
+	}{Woord: r.PathValue("woord"), List: list}
+	return c.templates.Execute(w, "example-form.html", data)
+}
+
+func (c *Context) addExample(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	example := Example{
+		Zin:       strings.TrimSpace(normalizeText(r.PostFormValue("zin"), list.SourceLanguage)),
+		Vertaling: strings.TrimSpace(normalizeText(r.PostFormValue("vertaling"), list.TargetLanguage)),
+	}
+	if example.Zin == "" || len([]rune(example.Zin)) > maxExampleLength || len([]rune(example.Vertaling)) > maxExampleLength {
+		return newHTTPError(http.StatusBadRequest, responseBadExample, nil)
+	}
+
+	_, err = c.store.AddExample(user_id, list.ID, r.PathValue("woord"), example)
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	return err
+}
+
+func (c *Context) deleteExample(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, "<p>Bad example number</p>", err)
+	}
+	err = c.store.DeleteExample(user_id, list.ID, r.PathValue("woord"), id)
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such example</p>", nil)
+	}
+	return err
+}
+
+type reviewTmplData struct {
+	Word    Word
+	Example Example
+	Cloze   Cloze
+	List    List
+
+	// Set once an answer has been sent
+	Answered bool
+	Answer   string
+	Correct  bool
+}
+
+// Sends a cloze exercise made from a random example sentence in the list
+func (c *Context) review(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	// Only words with example sentences, the rest of the list can't give an exercise
+	words, err := c.store.SearchWords(user_id, WordFilter{List: list.ID, HasExamples: true})
+	if err != nil {
+		return err
+	}
+
+	var exercises []reviewTmplData
+	for _, word := range words {
+		forms := clozeForms(word, list)
+		for _, example := range word.Voorbeelden {
+			// Examples that don't contain a known form of their word can't be turned into an exercise
+			if cloze, ok := makeCloze(example.Zin, forms); ok {
+				exercises = append(exercises, reviewTmplData{Word: word, Example: example, Cloze: cloze, List: list})
+			}
+		}
+	}
+	if len(exercises) == 0 {
+		w.Write([]byte(responseNoExamples))
+		return nil
+	}
+	return c.templates.Execute(w, "review.html", exercises[rand.Intn(len(exercises))])
+}
+
+// Checks the answer to a cloze exercise and shows the whole sentence
+func (c *Context) checkReview(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	word, err := c.store.Word(user_id, list.ID, r.PostFormValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := reviewTmplData{Word: word, List: list, Answered: true, Answer: r.PostFormValue("answer")}
+	id, _ := strconv.Atoi(r.PostFormValue("example"))
+	for _, example := range word.Voorbeelden {
+		if example.ID == id {
+			data.Example = example
+		}
+	}
+	cloze, ok := makeCloze(data.Example.Zin, clozeForms(word, list))
+	if !ok {
+		return newHTTPError(http.StatusNotFound, "<p>No such example</p>", nil)
+	}
+	data.Cloze = cloze
+	data.Correct = cloze.Check(normalizeText(data.Answer, list.SourceLanguage))
+	return c.templates.Execute(w, "review.html", data)
+}
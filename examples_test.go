@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeCloze(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		forms    []string
+		cloze    Cloze
+		ok       bool
+	}{
+		{name: "The word itself", sentence: "De fiets staat buiten.", forms: []string{"fiets"},
+			cloze: Cloze{Before: "De ", Answer: "fiets", After: " staat buiten."}, ok: true},
+		{name: "Capitalised", sentence: "Fietsen is gezond.", forms: []string{"fiets", "fietsen"},
+			cloze: Cloze{Answer: "Fietsen", After: " is gezond."}, ok: true},
+		{name: "Part of a longer word", sentence: "Mijn fietsenstalling is vol.", forms: []string{"fiets", "fietsen"}, ok: false},
+		{name: "Apostrophe", sentence: "Twee auto's rijden voorbij.", forms: []string{"auto", "auto's"},
+			cloze: Cloze{Before: "Twee ", Answer: "auto's", After: " rijden voorbij."}, ok: true},
+		{name: "Quoted", sentence: "Hij zei 'werk'.", forms: []string{"werk"},
+			cloze: Cloze{Before: "Hij zei '", Answer: "werk", After: "'."}, ok: true},
+		{name: "Two words", sentence: "Ik sta op om zeven uur.", forms: []string{"opstaan", "sta op"},
+			cloze: Cloze{Before: "Ik ", Answer: "sta op", After: " om zeven uur."}, ok: true},
+		{name: "Accents", sentence: "Wir essen Äpfel.", forms: []string{"Apfel", "äpfel"},
+			cloze: Cloze{Before: "Wir essen ", Answer: "Äpfel", After: "."}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloze, ok := makeCloze(tt.sentence, tt.forms)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.cloze, cloze)
+		})
+	}
+}
+
+func TestClozeForms(t *testing.T) {
+	werken := Word{Woord: "werken", Senses: []Sense{{Woordsoort: "ww"}}}
+	dutch := List{SourceLanguage: "nl"}
+	assert.Contains(t, clozeForms(werken, dutch), "werkte", "the suggested conjugation is used for Dutch verbs")
+	assert.NotContains(t, clozeForms(werken, List{SourceLanguage: "de"}), "werkte")
+
+	huis := Word{Woord: "huis", Meervoud: "huizen", Verkleinwoord: "huisje; huisken"}
+	assert.Equal(t, []string{"huis", "huizen", "huisje", "huisken"}, clozeForms(huis, dutch))
+
+	cloze, _ := makeCloze("Ik werkte gisteren.", clozeForms(werken, dutch))
+	assert.True(t, cloze.Check(" WERKTE "))
+	assert.False(t, cloze.Check("werkt"))
+}
+
+func TestExampleHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("POST /examples/{woord}", appHandler(c.addExample))
+	router.Handle("DELETE /examples/{woord}/{id}", appHandler(c.deleteExample))
+	router.Handle("GET /review", appHandler(c.review))
+	router.Handle("POST /review", appHandler(c.checkReview))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Contains(t, do("GET", "/review", "").Body.String(), "nog geen voorbeeldzinnen")
+
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=werken&woordsoort=ww&vertaling=to work").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/examples/werken", "zin=Ik werkte <i>gisteren</i> thuis.&vertaling=I worked from home yesterday.").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/examples/werken", "zin= ").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/examples/spelen", "zin=Wij spelen.").Code)
+
+	table := do("POST", "/", "search=").Body.String()
+	assert.Contains(t, table, "Ik werkte &lt;i&gt;gisteren&lt;/i&gt; thuis.")
+	assert.Contains(t, table, "I worked from home yesterday.")
+
+	review := do("GET", "/review", "").Body.String()
+	assert.Contains(t, review, `Ik <input class="word" type="text" name="answer"`)
+	assert.NotContains(t, review, "werkte", "the inflected form is blanked")
+
+	checked := do("POST", "/review", "woord=werken&example=1&answer=werkte").Body.String()
+	assert.Contains(t, checked, "Goed!")
+	checked = do("POST", "/review", "woord=werken&example=1&answer=werkt").Body.String()
+	assert.Contains(t, checked, "<s>werkt</s>")
+	assert.Equal(t, http.StatusNotFound, do("POST", "/review", "woord=werken&example=7&answer=werkt").Code)
+
+	require.Equal(t, http.StatusOK, do("DELETE", "/examples/werken/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/examples/werken/1", "").Code)
+	assert.NotContains(t, do("POST", "/", "search=").Body.String(), "thuis")
+}
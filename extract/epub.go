@@ -0,0 +1,158 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// How much XHTML is read from a book, a compressed file can claim to be much larger than it is
+const maxEPUBContent = 64 << 20
+
+// EPUB extracts the text of the chapters of an EPUB book in reading order, one paragraph per line
+func EPUB(data []byte) (string, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("reading EPUB: %w", err)
+	}
+	budget := int64(maxEPUBContent)
+	open := func(name string) ([]byte, error) {
+		f, err := z.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		content, err := io.ReadAll(io.LimitReader(f, budget+1))
+		if err != nil {
+			return nil, err
+		}
+		budget -= int64(len(content))
+		if budget < 0 {
+			return nil, errors.New("EPUB is too large")
+		}
+		return content, nil
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	content, err := open("META-INF/container.xml")
+	if err != nil {
+		return "", fmt.Errorf("reading EPUB container: %w", err)
+	}
+	if err := xml.Unmarshal(content, &container); err != nil || len(container.Rootfiles) == 0 {
+		return "", fmt.Errorf("EPUB container doesn't name a package: %v", err)
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg struct {
+		Items []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if content, err = open(opfPath); err != nil {
+		return "", fmt.Errorf("reading EPUB package: %w", err)
+	}
+	if err := xml.Unmarshal(content, &pkg); err != nil {
+		return "", fmt.Errorf("parsing EPUB package: %w", err)
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range pkg.Items {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+	var text strings.Builder
+	for _, itemref := range pkg.Spine {
+		href, ok := hrefs[itemref.IDRef]
+		if !ok {
+			continue
+		}
+		// Hrefs are URLs relative to the package file
+		href, _, _ = strings.Cut(href, "#")
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		chapter, err := open(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			return "", fmt.Errorf("reading EPUB chapter %s: %w", href, err)
+		}
+		if err := htmlText(&text, chapter); err != nil {
+			return "", fmt.Errorf("parsing EPUB chapter %s: %w", href, err)
+		}
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// Elements that end a line of text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "blockquote": true, "section": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// Elements whose content isn't text of the book
+var skippedElements = map[string]bool{"head": true, "script": true, "style": true}
+
+// Writes the text of an XHTML document, which is parsed leniently because not every book is valid XML
+func htmlText(w *strings.Builder, document []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(document))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	skipping := 0
+	var line strings.Builder
+	endLine := func() {
+		if s := strings.Join(strings.Fields(line.String()), " "); s != "" {
+			w.WriteString(s + "\n")
+		}
+		line.Reset()
+	}
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skippedElements[name] {
+				skipping++
+			}
+			if blockElements[name] {
+				endLine()
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if skippedElements[name] && skipping > 0 {
+				skipping--
+			}
+			if blockElements[name] {
+				endLine()
+			}
+		case xml.CharData:
+			if skipping == 0 {
+				line.Write(t)
+			}
+		}
+	}
+	endLine()
+	return nil
+}
@@ -0,0 +1,24 @@
+// Package extract gets the plain text out of files people read and watch in another language:
+// SRT and WebVTT subtitles and EPUB books. Lines of the text are paragraphs or sentences,
+// markup and timing are left out.
+package extract
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported is returned for files that are neither subtitles nor an EPUB book
+var ErrUnsupported = errors.New("only .srt, .vtt and .epub files are supported")
+
+// Text extracts the text of a file, its name tells what kind of file it is
+func Text(name string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".srt", ".vtt":
+		return Subtitles(data), nil
+	case ".epub":
+		return EPUB(data)
+	}
+	return "", ErrUnsupported
+}
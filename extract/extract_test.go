@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRT(t *testing.T) {
+	srt := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Waar is</i> {\\an8}mijn fiets?\r\n\r\n" +
+		"2\r\n00:00:03,000 --> 00:00:04,000\r\n- Ik heb hem\r\n- niet gezien\r\n\r\n" +
+		"3\r\n00:00:04,500 --> 00:00:05,000\r\nbij het station.\r\n"
+	assert.Equal(t, "Waar is mijn fiets?\nIk heb hem niet gezien bij het station.", Subtitles([]byte(srt)))
+}
+
+func TestVTT(t *testing.T) {
+	vtt := "WEBVTT - Aflevering 1\n\nNOTE dit is geen tekst\n\nSTYLE\n::cue { color: yellow }\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start\n<v Anna>Goedemorgen!</v>\n\n" +
+		"00:02.500 --> 00:04.000\nHoe <00:03.000>gaat <c.loud>het</c>?\n"
+	text, err := Text("aflevering.VTT", []byte(vtt))
+	require.NoError(t, err)
+	assert.Equal(t, "Goedemorgen!\nHoe gaat het?", text)
+}
+
+// Builds an EPUB with its package in a directory, like most books have
+func makeEPUB(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := z.Create(name)
+		require.NoError(t, err)
+		w.Write([]byte(content))
+	}
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func TestEPUB(t *testing.T) {
+	book := makeEPUB(t, map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?><package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+			<manifest>
+				<item id="css" href="style.css" media-type="text/css"/>
+				<item id="h2" href="text/hoofdstuk%202.xhtml" media-type="application/xhtml+xml"/>
+				<item id="h1" href="text/hoofdstuk1.xhtml" media-type="application/xhtml+xml"/>
+			</manifest>
+			<spine><itemref idref="h1"/><itemref idref="h2"/></spine></package>`,
+		"OEBPS/text/hoofdstuk1.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Titel</title><style>p { margin: 0 }</style></head>
+			<body><h1>Hoofdstuk 1</h1><p>Het was een  koude
+			dag.</p><p>Ze nam de&nbsp;fiets.<br/>Niemand zag <em>haar</em>.</p></body></html>`,
+		"OEBPS/text/hoofdstuk 2.xhtml": `<html><body><p>Einde</p><script>var x = 1;</script></body></html>`,
+	})
+
+	text, err := Text("boek.epub", book)
+	require.NoError(t, err)
+	assert.Equal(t, "Hoofdstuk 1\nHet was een koude dag.\nZe nam de fiets.\nNiemand zag haar.\nEinde", text)
+
+	_, err = EPUB([]byte("not a zip"))
+	assert.Error(t, err)
+	_, err = EPUB(makeEPUB(t, map[string]string{"mimetype": "application/epub+zip"}))
+	assert.Error(t, err, "no container")
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := Text("boek.pdf", []byte("%PDF"))
+	assert.Equal(t, ErrUnsupported, err)
+}
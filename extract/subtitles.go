@@ -0,0 +1,62 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// <i>, </font>, <v Anna>, <00:00:01.000> and the {\an8} positioning of SRT
+	subtitleMarkup = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+	// The dash that starts a line of dialogue
+	dialogueDash = regexp.MustCompile(`^[-–—]\s*`)
+)
+
+// Subtitles extracts the text of an SRT or WebVTT file. Only the lines after a timing line are text,
+// which leaves out cue numbers, the WEBVTT header and NOTE and STYLE blocks.
+// A sentence that goes on in the next cue stays on one line.
+func Subtitles(data []byte) string {
+	s := strings.TrimPrefix(string(data), "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var text strings.Builder
+	for _, block := range strings.Split(s, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		var cue []string
+		for _, line := range lines[timing+1:] {
+			line = subtitleMarkup.ReplaceAllString(line, "")
+			line = strings.TrimSpace(dialogueDash.ReplaceAllString(strings.TrimSpace(line), ""))
+			if line != "" {
+				cue = append(cue, line)
+			}
+		}
+		if len(cue) == 0 {
+			continue
+		}
+		if text.Len() > 0 {
+			if endsSentence(text.String()) {
+				text.WriteString("\n")
+			} else {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(strings.Join(cue, " "))
+	}
+	return text.String()
+}
+
+func endsSentence(s string) bool {
+	s = strings.TrimRight(s, `"'”’)`)
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "!") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "…") || strings.HasSuffix(s, ":")
+}
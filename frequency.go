@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Svuvi/wordsearch/dutch"
+)
+
+const (
+	// How many words the page with frequent words suggests at once
+	frequentSuggestions = 20
+	// Words of the frequency list read at a time while looking for ones that aren't saved
+	frequentBatch = 200
+)
+
+// WordFrequency is a word of a frequency list, not owned by any user
+type WordFrequency struct {
+	Woord  string
+	Aantal int64 // how often it occurs in the corpus
+	Rang   int   // 1 for the most frequent word
+}
+
+// Reads a frequency list with a word and its count on every line: SUBTLEX tab-separated files with a header,
+// where the count is the first number after the word, and the "word count" lists made from OpenSubtitles.
+// Spellings that only differ in case are counted together under the most frequent one, most frequent word first.
+func readFrequencyList(r io.Reader) ([]WordFrequency, error) {
+	scanner := bufio.NewScanner(r)
+	var words []WordFrequency
+	byKey := map[string]int{}
+	spelling := map[string]int64{} // count of the spelling that is kept
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimPrefix(scanner.Text(), "\ufeff")
+		var fields []string
+		if strings.Contains(text, "\t") {
+			fields = strings.Split(text, "\t")
+		} else {
+			fields = strings.Fields(text)
+		}
+		if len(fields) == 0 || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		word := strings.Trim(strings.TrimSpace(fields[0]), `"`)
+		count, ok := int64(0), false
+		for _, field := range fields[1:] {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+				count, ok = int64(f), true
+				break
+			}
+		}
+		if !ok {
+			// The header of SUBTLEX files names the columns
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: no count after %q", line, word)
+		}
+		if word == "" {
+			continue
+		}
+
+		key := strings.ToLower(word)
+		i, seen := byKey[key]
+		if !seen {
+			byKey[key] = len(words)
+			spelling[key] = count
+			words = append(words, WordFrequency{Woord: word, Aantal: count})
+			continue
+		}
+		if count > spelling[key] {
+			words[i].Woord, spelling[key] = word, count
+		}
+		words[i].Aantal += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	sort.SliceStable(words, func(i, j int) bool { return words[i].Aantal > words[j].Aantal })
+	for i := range words {
+		words[i].Rang = i + 1
+	}
+	return words, nil
+}
+
+// wordsearch import-frequencies <language> <list.txt>
+// Replaces the frequency list of the language, the file can be gzipped.
+func runImportFrequencies(cfg Config, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("import-frequencies: expected a language and a frequency list")
+	}
+	language, err := parseLanguage(args[0])
+	if err != nil {
+		return fmt.Errorf("import-frequencies: %w", err)
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var list io.Reader = f
+	if strings.HasSuffix(args[1], ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", args[1], err)
+		}
+		list = zr
+	}
+	words, err := readFrequencyList(list)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", args[1], err)
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := migrateToLatest(store); err != nil {
+		return err
+	}
+
+	if err := store.ImportFrequencies(baseLanguage(language), words); err != nil {
+		return fmt.Errorf("importing %s: %w", args[1], err)
+	}
+	fmt.Fprintf(out, "imported %d %s word frequencies\n", len(words), languageName(language))
+	return nil
+}
+
+// Fills in the rank of the words in the frequency list of the language
+func (c *Context) rankWords(words []Word, list List) error {
+	woorden := make([]string, len(words))
+	for i, word := range words {
+		woorden[i] = word.Woord
+	}
+	ranks, err := c.store.FrequencyRanks(baseLanguage(list.SourceLanguage), woorden)
+	if err != nil {
+		return err
+	}
+	for i := range words {
+		words[i].Rang = ranks[words[i].Woord]
+	}
+	return nil
+}
+
+// Most frequent first, words that aren't in the frequency list last
+func sortByFrequency(words []Word) {
+	sort.SliceStable(words, func(i, j int) bool {
+		if words[i].Rang == 0 || words[j].Rang == 0 {
+			return words[j].Rang == 0 && words[i].Rang != 0
+		}
+		return words[i].Rang < words[j].Rang
+	})
+}
+
+// The most frequent words of the language that aren't saved in the list, a form counts as saved when its lemma is.
+// Numbers, single letters and punctuation in the frequency list are skipped.
+func (c *Context) frequentUnknownWords(list List, saved []Word, n int) ([]WordFrequency, error) {
+	known := knownForms(saved, list)
+	var unknown []WordFrequency
+	after := 0
+	for len(unknown) < n {
+		words, err := c.store.FrequentWords(baseLanguage(list.SourceLanguage), after, frequentBatch)
+		if err != nil || len(words) == 0 {
+			return unknown, err
+		}
+		after = words[len(words)-1].Rang
+		for _, word := range words {
+			key := strings.ToLower(normalizeText(word.Woord, list.SourceLanguage))
+			if utf8.RuneCountInString(key) < 2 || strings.IndexFunc(key, unicode.IsLetter) < 0 || known[key] {
+				continue
+			}
+			if list.IsDutch() && slices.ContainsFunc(dutch.Lemmas(key), func(lemma string) bool { return known[lemma] }) {
+				continue
+			}
+			if unknown = append(unknown, word); len(unknown) == n {
+				break
+			}
+		}
+	}
+	return unknown, nil
+}
+
+// A frequent word with what the dictionaries know about it, for frequent.html
+type frequentWord struct {
+	minedWord
+	Rang int
+}
+
+type frequentTmplData struct {
+	List  List
+	Words []frequentWord
+}
+
+// Suggests the most frequent words of the language that the user hasn't saved yet
+func (c *Context) frequentWords(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	saved, err := c.store.SearchWords(user_id, WordFilter{List: list.ID})
+	if err != nil {
+		return err
+	}
+	unknown, err := c.frequentUnknownWords(list, saved, frequentSuggestions)
+	if err != nil {
+		return err
+	}
+
+	data := frequentTmplData{List: list}
+	for _, word := range unknown {
+		entry, err := c.lookupWord(list, word.Woord, "")
+		if err != nil {
+			return err
+		}
+		mined := minedWord{Woord: normalizeText(word.Woord, list.SourceLanguage), Entry: entry}
+		data.Words = append(data.Words, frequentWord{minedWord: mined, Rang: word.Rang})
+	}
+	return c.templates.Execute(w, "frequent.html", data)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFrequencyList(t *testing.T) {
+	subtlex := "Word\tFREQcount\tCDcount\tFREQlow\n" +
+		"huis\t2049\t1500\t2000\n" +
+		"de\t950000\t8000\t900000\n" +
+		"Huis\t51\t40\t10\n" +
+		"\n"
+	words, err := readFrequencyList(strings.NewReader(subtlex))
+	require.NoError(t, err)
+	assert.Equal(t, []WordFrequency{{Woord: "de", Aantal: 950000, Rang: 1}, {Woord: "huis", Aantal: 2100, Rang: 2}}, words, "spellings are counted together")
+
+	words, err = readFrequencyList(strings.NewReader("\ufeffik 3000\nje 2900\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []WordFrequency{{Woord: "ik", Aantal: 3000, Rang: 1}, {Woord: "je", Aantal: 2900, Rang: 2}}, words)
+
+	_, err = readFrequencyList(strings.NewReader("ik 3000\nje veel\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestFrequentWords(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	_, err = c.store.ImportReference("nl", "en", readWiktextract(strings.NewReader(testDump), "nl"))
+	require.NoError(t, err)
+	words, err := readFrequencyList(strings.NewReader("de 900\n, 800\nik 700\nfietsen 600\nhuis 500\n3 400\nkat 300\n"))
+	require.NoError(t, err)
+	require.NoError(t, c.store.ImportFrequencies("nl", words))
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /{$}", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("GET /frequent", appHandler(c.frequentWords))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=kat").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=fiets&lidwoord=de").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=ik").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=zwaluw").Code)
+
+	// Saved words show their rank and can be sorted by it
+	body := do("POST", "/", "sort=freq").Body.String()
+	assert.Contains(t, body, `ik <span class="rank" title="rank in the frequency list of the language">#3</span>`)
+	assert.NotContains(t, body, `zwaluw <span class="rank"`, "words that aren't in the frequency list have no rank")
+	ik, kat, zwaluw := strings.Index(body, "ik <span"), strings.Index(body, "kat <span"), strings.Index(body, "zwaluw")
+	assert.Less(t, ik, kat)
+	assert.Less(t, kat, zwaluw, "words without a rank come last")
+
+	rr := do("GET", "/frequent", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	body = rr.Body.String()
+	assert.Contains(t, body, `<b lang="nl">de</b> <span class="rank" title="rank in the frequency list">#1</span>`)
+	assert.Contains(t, body, `<b lang="nl">huis</b>`)
+	assert.Contains(t, body, `<input type="hidden" name="vertaling" value="house; home; household">`, "suggestions come with what Wiktionary knows")
+	assert.NotContains(t, body, `<b lang="nl">fietsen</b>`, "the plural of a saved noun is known")
+	assert.NotContains(t, body, `<b lang="nl">ik</b>`)
+	assert.NotContains(t, body, `<b lang="nl">,</b>`, "punctuation is skipped")
+	assert.NotContains(t, body, `<b lang="nl">3</b>`)
+	assert.Less(t, strings.Index(body, `<b lang="nl">de</b>`), strings.Index(body, `<b lang="nl">huis</b>`))
+}
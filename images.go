@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"strconv"
+
+	// Formats that can be uploaded, everything is saved as JPEG
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	imageMaxSide     = 1024
+	thumbnailMaxSide = 96
+	imageQuality     = 85
+	// Decoding needs memory for every pixel, so a small file that claims to be huge is refused before decoding
+	imageMaxPixels = 40_000_000
+)
+
+var errQuotaExceeded = errors.New("image quota exceeded")
+
+var responseBadImage = `<p>Only JPEG, PNG and GIF pictures can be uploaded</p>`
+
+// Image is the picture of a word, both sizes are JPEG
+type Image struct {
+	Data      []byte
+	Thumbnail []byte
+}
+
+// The stored size of the picture, which is what counts towards the quota
+func (i Image) size() int64 {
+	return int64(len(i.Data) + len(i.Thumbnail))
+}
+
+// Decodes an uploaded picture and encodes it again as a JPEG and a thumbnail, both no larger than their maximum side
+func processImage(r io.Reader) (Image, error) {
+	var buf bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return Image{}, err
+	}
+	if config.Width*config.Height > imageMaxPixels {
+		return Image{}, fmt.Errorf("picture of %dx%d is too large", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return Image{}, err
+	}
+
+	var processed Image
+	if processed.Data, err = encodeJPEG(scaleDown(img, imageMaxSide)); err != nil {
+		return Image{}, err
+	}
+	if processed.Thumbnail, err = encodeJPEG(scaleDown(img, thumbnailMaxSide)); err != nil {
+		return Image{}, err
+	}
+	return processed, nil
+}
+
+// Fits the picture within maxSide by maxSide, on white because JPEG has no transparency
+func scaleDown(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			width, height = maxSide, max(1, height*maxSide/width)
+		} else {
+			width, height = max(1, width*maxSide/height), maxSide
+		}
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+	return scaled
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sends the form for uploading the picture of a word
+func (c *Context) imageForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Woord string
+		List  int
+	}{Woord: r.PathValue("woord"), List: list.ID}
+	return c.templates.Execute(w, "image-form.html", data)
+}
+
+// Takes a multipart upload with the picture in the image field
+func (c *Context) uploadImage(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	limit := c.config.MaxImageSize
+	tooLarge := fmt.Sprintf("<p>Pictures can be at most %d kB</p>", limit/1024)
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return newHTTPError(http.StatusRequestEntityTooLarge, tooLarge, err)
+		}
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadImage, err)
+	}
+	defer file.Close()
+	if header.Size > limit {
+		return newHTTPError(http.StatusRequestEntityTooLarge, tooLarge, nil)
+	}
+	img, err := processImage(file)
+	if err != nil {
+		return newHTTPError(http.StatusUnsupportedMediaType, responseBadImage, err)
+	}
+
+	err = c.store.SaveImage(user_id, list.ID, r.PathValue("woord"), img, c.config.ImageQuota)
+	switch {
+	case err == errNotFound:
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	case err == errQuotaExceeded:
+		msg := fmt.Sprintf("<p>Your pictures can take at most %d MB, delete some first</p>", c.config.ImageQuota>>20)
+		return newHTTPError(http.StatusRequestEntityTooLarge, msg, err)
+	}
+	return err
+}
+
+// Serves the picture of a word, the thumbnail with size=thumb
+func (c *Context) serveImage(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	img, err := c.store.Image(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No picture</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := img.Data
+	if r.URL.Query().Get("size") == "thumb" {
+		data = img.Thumbnail
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Write(data)
+	return nil
+}
+
+func (c *Context) deleteImage(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	return c.store.DeleteImage(user_id, list.ID, r.PathValue("woord"))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// A PNG header that claims a size without the pixels to go with it
+func hugePNGHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 2 // 8 bit RGB
+	chunk := binary.BigEndian.AppendUint32(nil, 13)
+	chunk = append(chunk, ihdr...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(ihdr))
+	return append([]byte("\x89PNG\r\n\x1a\n"), chunk...)
+}
+
+func decodedSize(t *testing.T, data []byte) image.Point {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img.Bounds().Size()
+}
+
+func TestProcessImage(t *testing.T) {
+	img, err := processImage(bytes.NewReader(testPNG(t, 2000, 1000)))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(1024, 512), decodedSize(t, img.Data))
+	assert.Equal(t, image.Pt(96, 48), decodedSize(t, img.Thumbnail))
+
+	img, err = processImage(bytes.NewReader(testPNG(t, 30, 60)))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(30, 60), decodedSize(t, img.Data), "small pictures keep their size")
+
+	_, err = processImage(strings.NewReader("<svg onload=alert(1)>"))
+	assert.Error(t, err)
+	_, err = processImage(bytes.NewReader(hugePNGHeader(20000, 20000)))
+	assert.ErrorContains(t, err, "too large")
+}
+
+func TestImageHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	db.Exec("INSERT INTO words (user_id, list_id, word) VALUES (1, 1, 'fiets'), (1, 1, 'huis')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("GET /image/{woord}", appHandler(c.serveImage))
+	router.Handle("POST /image/{woord}", appHandler(c.uploadImage))
+	router.Handle("DELETE /image/{woord}", appHandler(c.deleteImage))
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	upload := func(word string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("image", "plaatje.png")
+		part.Write(data)
+		form.Close()
+		req, _ := http.NewRequest("POST", "/image/"+word, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return do(req)
+	}
+	get := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return do(req)
+	}
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload("fiets", []byte("<html></html>")).Code)
+	assert.Equal(t, http.StatusNotFound, upload("lopen", testPNG(t, 10, 10)).Code)
+	require.Equal(t, http.StatusOK, upload("fiets", testPNG(t, 400, 300)).Code)
+
+	rr := get("GET", "/image/fiets?size=thumb")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/jpeg", rr.Header().Get("Content-Type"))
+	assert.Equal(t, image.Pt(96, 72), decodedSize(t, rr.Body.Bytes()))
+	assert.Equal(t, image.Pt(400, 300), decodedSize(t, get("GET", "/image/fiets").Body.Bytes()))
+	assert.Contains(t, get("POST", "/").Body.String(), `src="/image/fiets?list=1&size=thumb"`)
+
+	saved, err := c.store.Image(1, 1, "fiets")
+	require.NoError(t, err)
+	c.config.ImageQuota = saved.size() + 10
+	require.Equal(t, http.StatusOK, upload("fiets", testPNG(t, 400, 300)).Code, "replacing doesn't count twice")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("huis", testPNG(t, 400, 300)).Code, "over the quota")
+
+	c.config.MaxImageSize = 100
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("huis", testPNG(t, 400, 300)).Code)
+
+	require.Equal(t, http.StatusOK, get("DELETE", "/image/fiets").Code)
+	assert.Equal(t, http.StatusNotFound, get("GET", "/image/fiets").Code)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// How many of the sentences a word was looked up in become its examples
+	kindleExamples = 3
+	// Imported words get this tag so they can be found and checked afterwards
+	kindleTag = "kindle"
+)
+
+var (
+	responseBadKindle      = `<p>Upload the vocab.db file from the system folder of a Kindle</p>`
+	responseKindleTooLarge = `<p>The file is too large for a vocab.db</p>`
+)
+
+// A book on the Kindle that words were looked up in
+type kindleBook struct {
+	ID      string
+	Titel   string
+	Auteurs string
+	Woorden int // words in the language of the list, filled in by kindleBooks
+}
+
+// A word looked up on the Kindle, once for every time it was looked up
+type kindleLookup struct {
+	Woord string // as it was in the book
+	Stam  string // the dictionary form the Kindle found, can be empty
+	Taal  string
+	Zin   string // the sentence it was looked up in
+	Boek  string // id of the book
+}
+
+// Reads the books and lookups from the vocab.db of the Kindle's Vocabulary Builder, oldest lookup first
+func readKindle(path string) ([]kindleBook, []kindleLookup, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+	// The file comes from the user, its views and triggers must not call functions with side effects.
+	// One connection so the pragma holds for every query.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA trusted_schema = OFF"); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query("SELECT id, COALESCE(title, ''), COALESCE(authors, '') FROM BOOK_INFO ORDER BY title")
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading books: %w", err)
+	}
+	defer rows.Close()
+	var books []kindleBook
+	for rows.Next() {
+		var book kindleBook
+		if err := rows.Scan(&book.ID, &book.Titel, &book.Auteurs); err != nil {
+			return nil, nil, err
+		}
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = db.Query(`SELECT COALESCE(w.word, ''), COALESCE(w.stem, ''), COALESCE(w.lang, ''), COALESCE(l.usage, ''), COALESCE(l.book_key, '')
+		FROM LOOKUPS l JOIN WORDS w ON w.id = l.word_key ORDER BY l.timestamp`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading lookups: %w", err)
+	}
+	defer rows.Close()
+	var lookups []kindleLookup
+	for rows.Next() {
+		var lookup kindleLookup
+		if err := rows.Scan(&lookup.Woord, &lookup.Stam, &lookup.Taal, &lookup.Zin, &lookup.Boek); err != nil {
+			return nil, nil, err
+		}
+		lookups = append(lookups, lookup)
+	}
+	return books, lookups, rows.Err()
+}
+
+// The books with words in the language of the list, with how many different words there are
+func kindleBooks(books []kindleBook, lookups []kindleLookup, list List) []kindleBook {
+	words := map[string]map[string]bool{}
+	for _, lookup := range lookups {
+		if baseLanguage(lookup.Taal) != baseLanguage(list.SourceLanguage) {
+			continue
+		}
+		if words[lookup.Boek] == nil {
+			words[lookup.Boek] = map[string]bool{}
+		}
+		words[lookup.Boek][lookup.word()] = true
+	}
+	var withWords []kindleBook
+	for _, book := range books {
+		if book.Woorden = len(words[book.ID]); book.Woorden > 0 {
+			withWords = append(withWords, book)
+		}
+	}
+	return withWords
+}
+
+// Kindles only find a stem for words their dictionary knows
+func (l kindleLookup) word() string {
+	if l.Stam != "" {
+		return l.Stam
+	}
+	return l.Woord
+}
+
+// The words looked up in the books in the language of the list, in the order they were first looked up,
+// with the sentences they were looked up in as examples
+func kindleWords(lookups []kindleLookup, books []string, list List) []Word {
+	var words []Word
+	index := map[string]int{}
+	for _, lookup := range lookups {
+		if baseLanguage(lookup.Taal) != baseLanguage(list.SourceLanguage) || !slices.Contains(books, lookup.Boek) {
+			continue
+		}
+		woord := strings.TrimSpace(normalizeText(lookup.word(), list.SourceLanguage))
+		if woord == "" {
+			continue
+		}
+		i, ok := index[woord]
+		if !ok {
+			i = len(words)
+			index[woord] = i
+			words = append(words, Word{Woord: woord, Tags: []string{kindleTag}})
+		}
+		zin := strings.Join(strings.Fields(normalizeText(lookup.Zin, list.SourceLanguage)), " ")
+		word := &words[i]
+		if zin != "" && len([]rune(zin)) <= maxExampleLength && len(word.Voorbeelden) < kindleExamples &&
+			!slices.ContainsFunc(word.Voorbeelden, func(e Example) bool { return e.Zin == zin }) {
+			word.Voorbeelden = append(word.Voorbeelden, Example{Zin: zin})
+		}
+	}
+	return words
+}
+
+// Adds the words to the list with what the dictionaries know about them, skipping the ones that are saved already
+func (c *Context) importKindleWords(user_id int, list List, words []Word) (added, existing int, err error) {
+	for _, word := range words {
+		entry, err := c.lookupWord(list, word.Woord, "")
+		if err != nil {
+			return added, existing, err
+		}
+		word.Uitspraak = entry.Pronunciation
+		word.Senses = []Sense{{Woordsoort: entry.PartOfSpeech, Vertaling: strings.Join(entry.Translations, "; ")}}
+		normalizeWord(&word, list)
+
+		err = c.store.AddWord(user_id, list.ID, word, conflictAsk)
+		var exists *WordExistsError
+		if errors.As(err, &exists) {
+			existing++
+			continue
+		}
+		if err != nil {
+			return added, existing, err
+		}
+		added++
+	}
+	return added, existing, nil
+}
+
+type kindleTmplData struct {
+	List     List
+	Books    []kindleBook
+	Imported bool
+	Added    int
+	Existing int
+}
+
+// Sends the form for uploading a vocab.db
+func (c *Context) kindleForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	return c.templates.Execute(w, "kindle-form.html", kindleTmplData{List: list})
+}
+
+// Lists the books of an uploaded vocab.db with words in the language of the list, or imports the words of the
+// books that were picked from that list
+func (c *Context) importKindle(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	// Room for the other fields and the multipart boundaries
+	limit := c.config.MaxKindleDBSize
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newHTTPError(http.StatusRequestEntityTooLarge, responseKindleTooLarge, err)
+		}
+		return newHTTPError(http.StatusBadRequest, responseBadKindle, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadKindle, err)
+	}
+	defer file.Close()
+
+	// SQLite needs a file to open
+	tmp, err := os.CreateTemp("", "vocab-*.db")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, []byte("SQLite format 3\x00")) {
+		return newHTTPError(http.StatusBadRequest, responseBadKindle, err)
+	}
+	if _, err := tmp.Write(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	books, lookups, err := readKindle(tmp.Name())
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadKindle, err)
+	}
+
+	data := kindleTmplData{List: list, Books: kindleBooks(books, lookups, list)}
+	if r.PostFormValue("import") != "" {
+		words := kindleWords(lookups, r.PostForm["boek"], list)
+		if data.Added, data.Existing, err = c.importKindleWords(user_id, list, words); err != nil {
+			return err
+		}
+		data.Imported = true
+	}
+	return c.templates.Execute(w, "kindle-books.html", data)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Makes a vocab.db with the tables of the Kindle's Vocabulary Builder
+func writeVocabDB(t *testing.T) []byte {
+	path := filepath.Join(t.TempDir(), "vocab.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, query := range []string{
+		"CREATE TABLE BOOK_INFO (id TEXT PRIMARY KEY NOT NULL, asin TEXT, guid TEXT, lang TEXT, title TEXT, authors TEXT)",
+		"CREATE TABLE WORDS (id TEXT PRIMARY KEY NOT NULL, word TEXT, stem TEXT, lang TEXT, category INTEGER DEFAULT 0, timestamp INTEGER DEFAULT 0, profileid TEXT)",
+		"CREATE TABLE LOOKUPS (id TEXT PRIMARY KEY NOT NULL, word_key TEXT, book_key TEXT, dict_key TEXT, pos TEXT, usage TEXT, timestamp INTEGER DEFAULT 0)",
+		`INSERT INTO BOOK_INFO (id, lang, title, authors) VALUES
+			('b1', 'nl', 'Het diner', 'Herman Koch'), ('b2', 'nl', 'De avonden', 'Gerard Reve'), ('b3', 'en', 'Dubliners', 'James Joyce')`,
+		`INSERT INTO WORDS (id, word, stem, lang) VALUES
+			('nl:huizen', 'huizen', 'huis', 'nl'), ('nl:huis', 'huis', 'huis', 'nl'), ('nl:fiets', 'fiets', 'fiets', 'nl'),
+			('nl:somber', 'somber', NULL, 'nl'), ('en:snow', 'snow', 'snow', 'en')`,
+		`INSERT INTO LOOKUPS (id, word_key, book_key, usage, timestamp) VALUES
+			('1', 'nl:huizen', 'b1', 'De huizen  waren oud.', 1), ('2', 'nl:huis', 'b1', 'Het huis stond leeg.', 2),
+			('3', 'nl:huizen', 'b1', 'De huizen  waren oud.', 3), ('4', 'nl:fiets', 'b1', 'Ik pak de fiets.', 4),
+			('5', 'nl:somber', 'b2', 'Het was een sombere dag.', 5), ('6', 'en:snow', 'b3', 'Snow was general.', 6)`,
+	} {
+		_, err := db.Exec(query)
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReadKindle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.db")
+	require.NoError(t, os.WriteFile(path, writeVocabDB(t), 0o600))
+	books, lookups, err := readKindle(path)
+	require.NoError(t, err)
+	require.Len(t, lookups, 6)
+	assert.Equal(t, kindleLookup{Woord: "somber", Taal: "nl", Zin: "Het was een sombere dag.", Boek: "b2"}, lookups[4])
+
+	dutch := List{SourceLanguage: "nl", TargetLanguage: "en"}
+	assert.Equal(t, []kindleBook{
+		{ID: "b2", Titel: "De avonden", Auteurs: "Gerard Reve", Woorden: 1},
+		{ID: "b1", Titel: "Het diner", Auteurs: "Herman Koch", Woorden: 2},
+	}, kindleBooks(books, lookups, dutch), "only books with words in the language of the list")
+
+	words := kindleWords(lookups, []string{"b1"}, dutch)
+	require.Len(t, words, 2)
+	assert.Equal(t, "huis", words[0].Woord, "forms are imported as their stem")
+	assert.Equal(t, []Example{{Zin: "De huizen waren oud."}, {Zin: "Het huis stond leeg."}}, words[0].Voorbeelden)
+	assert.Equal(t, []string{kindleTag}, words[0].Tags)
+	assert.Equal(t, "fiets", words[1].Woord)
+}
+
+func TestImportKindle(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	_, err = c.store.ImportReference("nl", "en", readWiktextract(strings.NewReader(testDump), "nl"))
+	require.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	require.NoError(t, c.store.AddWord(1, 1, Word{Woord: "fiets"}, conflictAsk))
+
+	router := http.NewServeMux()
+	router.Handle("GET /kindle", appHandler(c.kindleForm))
+	router.Handle("POST /kindle", appHandler(c.importKindle))
+	vocab := writeVocabDB(t)
+	upload := func(data []byte, fields ...string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "vocab.db")
+		fw.Write(data)
+		for i := 0; i < len(fields); i += 2 {
+			mw.WriteField(fields[i], fields[i+1])
+		}
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/kindle?list=1", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	req, _ := http.NewRequest("GET", "/kindle", nil)
+	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Contains(t, rr.Body.String(), `hx-post="/kindle?list=1"`)
+
+	// Uploading shows the books to pick from
+	rr = upload(vocab)
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, `<input type="checkbox" name="boek" value="b1" checked> Het diner`)
+	assert.NotContains(t, body, "Dubliners")
+	_, err = c.store.Word(1, 1, "huis")
+	assert.Equal(t, errNotFound, err, "nothing is imported yet")
+
+	rr = upload(vocab, "boek", "b1", "import", "1")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "1 woorden toegevoegd aan Mijn woorden, 1 stonden er al in")
+	huis, err := c.store.Word(1, 1, "huis")
+	require.NoError(t, err)
+	assert.Equal(t, "ɦœys", huis.Uitspraak, "imported words get what Wiktionary knows")
+	assert.Equal(t, "house; home; household", huis.Senses[0].Vertaling)
+	assert.Equal(t, []string{"kindle"}, huis.Tags)
+	require.Len(t, huis.Voorbeelden, 2)
+	assert.Equal(t, "De huizen waren oud.", huis.Voorbeelden[0].Zin)
+	_, err = c.store.Word(1, 1, "somber")
+	assert.Equal(t, errNotFound, err, "books that weren't picked are left out")
+
+	assert.Equal(t, http.StatusBadRequest, upload([]byte("not a database")).Code)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"golang.org/x/text/unicode/norm"
+)
+
+// New lists are for Dutch words with English translations unless the user picks something else
+const (
+	defaultSourceLanguage = "nl"
+	defaultTargetLanguage = "en"
+)
+
+var responseBadLanguage = `<p>Languages are BCP-47 tags like nl, de or en-GB</p>`
+
+// Offered in the language fields, any other valid tag works too
+var suggestedLanguages = []string{"nl", "de", "en", "fr", "es", "it", "pt", "sv", "da", "no", "pl", "ru", "uk", "tr", "ar", "zh", "ja"}
+
+type languageOption struct {
+	Tag  string
+	Name string
+}
+
+// The suggested languages with their names, for the language fields
+func languageOptions() []languageOption {
+	options := make([]languageOption, len(suggestedLanguages))
+	for i, tag := range suggestedLanguages {
+		options[i] = languageOption{Tag: tag, Name: languageName(tag)}
+	}
+	return options
+}
+
+// Spelling variants that are folded into one after Unicode normalization, by base language.
+// Dutch has a ligature for ij that some keyboards produce, and curly apostrophes come from phones.
+var spellingVariants = map[string]*strings.Replacer{
+	"nl": strings.NewReplacer("ĳ", "ij", "Ĳ", "IJ", "’", "'"),
+	"de": strings.NewReplacer("’", "'"),
+}
+
+// Parses a BCP-47 tag into its canonical form, "NL-be" becomes "nl-BE"
+func parseLanguage(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("empty language tag")
+	}
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
+
+// "nl" for nl-BE
+func baseLanguage(tag string) string {
+	base, _ := language.Make(tag).Base()
+	return base.String()
+}
+
+// The name of the language in the language itself: Nederlands, Deutsch
+func languageName(tag string) string {
+	if name := display.Self.Name(language.Make(tag)); name != "" {
+		return name
+	}
+	return tag
+}
+
+// NFC and then the spelling variants of the language, so the same word typed on two devices is saved once
+func normalizeText(s, tag string) string {
+	s = norm.NFC.String(s)
+	if replacer, ok := spellingVariants[baseLanguage(tag)]; ok {
+		s = replacer.Replace(s)
+	}
+	return s
+}
+
+// Normalizes the word and its forms as source language text and the translations as target language text
+func normalizeWord(word *Word, list List) {
+	for _, field := range []*string{&word.Woord, &word.Meervoud, &word.Verkleinwoord, &word.Uitspraak} {
+		*field = normalizeText(*field, list.SourceLanguage)
+	}
+	for i := range word.Senses {
+		sense := &word.Senses[i]
+		sense.Woordsoort = normalizeText(sense.Woordsoort, list.SourceLanguage)
+		sense.Vertaling = normalizeText(sense.Vertaling, list.TargetLanguage)
+		sense.Notes = normalizeText(sense.Notes, list.TargetLanguage)
+	}
+}
+
+// Sorts alphabetically the way a dictionary in that language would, ignoring case
+func sortWords(words []Word, tag string) {
+	collator := collate.New(language.Make(tag), collate.IgnoreCase)
+	sort.SliceStable(words, func(i, j int) bool {
+		return collator.CompareString(words[i].Woord, words[j].Woord) < 0
+	})
+}
+
+func (l List) SourceName() string {
+	return languageName(l.SourceLanguage)
+}
+
+func (l List) TargetName() string {
+	return languageName(l.TargetLanguage)
+}
+
+// The articles nouns in the list can take, none for languages we don't know the articles of
+func (l List) Articles() []string {
+	return articlesByLanguage[baseLanguage(l.SourceLanguage)]
+}
+
+// Plural, diminutive and conjugation suggestions only know Dutch
+func (l List) IsDutch() bool {
+	return baseLanguage(l.SourceLanguage) == "nl"
+}
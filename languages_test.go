@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLanguage(t *testing.T) {
+	tag, err := parseLanguage(" NL-be ")
+	assert.NoError(t, err)
+	assert.Equal(t, "nl-BE", tag)
+	assert.Equal(t, "nl", baseLanguage(tag))
+
+	_, err = parseLanguage("")
+	assert.Error(t, err)
+	_, err = parseLanguage("not a language")
+	assert.Error(t, err)
+}
+
+func TestLanguageName(t *testing.T) {
+	assert.Equal(t, "Nederlands", languageName("nl"))
+	assert.Equal(t, "Deutsch", languageName("de"))
+}
+
+func TestNormalizeText(t *testing.T) {
+	decomposed := "één"
+	assert.Equal(t, "één", normalizeText(decomposed, "en"), "composed into single code points")
+	assert.Equal(t, "ijs", normalizeText("ĳs", "nl"))
+	assert.Equal(t, "auto's", normalizeText("auto’s", "nl-BE"))
+	assert.Equal(t, "ĳs", normalizeText("ĳs", "de"), "the ligature is only folded for Dutch")
+}
+
+func TestSortWords(t *testing.T) {
+	words := []Word{{Woord: "Zug"}, {Woord: "Äpfel"}, {Woord: "apfel"}, {Woord: "Birne"}}
+	sortWords(words, "de")
+	var sorted []string
+	for _, word := range words {
+		sorted = append(sorted, word.Woord)
+	}
+	assert.Equal(t, []string{"apfel", "Äpfel", "Birne", "Zug"}, sorted)
+}
+
+func TestListLanguages(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+
+	router := http.NewServeMux()
+	router.Handle("GET /{$}", appHandler(c.indexPage))
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("GET /suggest", appHandler(c.suggest))
+	router.Handle("POST /lists", appHandler(c.createList))
+	router.Handle("POST /lists/{id}", appHandler(c.updateList))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/lists", "new_list=Duits&source_language=not a language").Code)
+	rr := do("POST", "/lists", "new_list=Duits&source_language=DE&target_language=en-GB")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "/?list=2", rr.Header().Get("HX-Redirect"))
+
+	// Dutch and German side by side, each with its own articles
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "list=1&woord=huis&lidwoord=het").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "list=1&woord=%C4%B3s&lidwoord=het").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "list=2&woord=Haus&lidwoord=das").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "list=2&woord=%C3%84pfel&lidwoord=der").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/add/", "list=2&woord=Apfel&lidwoord=het").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/add/", "list=1&woord=Haus&lidwoord=das").Code)
+
+	assert.Contains(t, do("POST", "/", "list=1&search=ijs").Body.String(), "<b>ijs</b>", "the ligature was saved as ij")
+	body := do("POST", "/", "list=2&search=&sort=alpha&filter_lidwoord=das").Body.String()
+	assert.Contains(t, body, "Haus")
+	assert.NotContains(t, body, "Äpfel")
+	body = do("POST", "/", "list=2&search=&sort=alpha").Body.String()
+	assert.Less(t, strings.Index(body, "Äpfel"), strings.Index(body, "Haus"), "sorted the German way")
+
+	page := do("GET", "/?list=2", "").Body.String()
+	assert.Contains(t, page, "typ het woord in (Deutsch)")
+	assert.Contains(t, page, `<option value="die/das">die/das</option>`)
+	assert.NotContains(t, page, `<option value="de/het">`)
+
+	assert.Contains(t, do("GET", "/suggest?list=1&woord=huis&lidwoord=het", "").Body.String(), "huizen")
+	assert.NotContains(t, do("GET", "/suggest?list=2&woord=Haus&lidwoord=das", "").Body.String(), "Hausen", "Dutch rules aren't used for German")
+
+	rr = do("POST", "/lists/2", "name=Deutsch&source_language=de-AT&target_language=nl")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, do("GET", "/?list=2", "").Body.String(), "vertaling (Nederlands)")
+	assert.Equal(t, http.StatusConflict, do("POST", "/lists/2", "name=Mijn woorden").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/lists/9", "name=Nergens").Code)
+}
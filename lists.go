@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Every user starts with one list, words saved before lists existed ended up there too
+const defaultListName = "Mijn woorden"
+
+var errListExists = errors.New("list already exists")
+
+var responseNoSuchList = `<p>No such list</p>`
+var responseBadListName = `<p>A list needs a name of at most 50 characters</p>`
+var responseListExists = `<p>You already have a list with that name</p>`
+
+// List is a named deck of words owned by one user
+type List struct {
+	ID    int
+	Name  string
+	Count int // number of words in the list
+
+	// BCP-47 tags of the language of the words and the language of their translations
+	SourceLanguage string
+	TargetLanguage string
+}
+
+// The list a request works on: the one in the list parameter, or the user's first list when there is none
+func (c *Context) requestList(r *http.Request, userID int) (List, error) {
+	param := r.FormValue("list")
+	if param == "" {
+		return c.store.DefaultList(userID)
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return List{}, newHTTPError(http.StatusBadRequest, responseNoSuchList, err)
+	}
+	list, err := c.store.List(userID, id)
+	if err == errNotFound {
+		return List{}, newHTTPError(http.StatusNotFound, responseNoSuchList, nil)
+	}
+	return list, err
+}
+
+func (c *Context) createList(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := listFromForm(r, "new_list")
+	if err != nil {
+		return err
+	}
+
+	list, err = c.store.CreateList(user_id, list)
+	if err == errListExists {
+		return newHTTPError(http.StatusConflict, responseListExists, err)
+	}
+	if err != nil {
+		return err
+	}
+	// The list selector is part of the page, so the page is loaded again with the new list picked
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/?list=%d", list.ID))
+	return nil
+}
+
+// Renames a list and changes its languages
+func (c *Context) updateList(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseNoSuchList, err)
+	}
+	list, err := listFromForm(r, "name")
+	if err != nil {
+		return err
+	}
+	list.ID = id
+
+	err = c.store.UpdateList(user_id, list)
+	switch {
+	case err == errListExists:
+		return newHTTPError(http.StatusConflict, responseListExists, err)
+	case err == errNotFound:
+		return newHTTPError(http.StatusNotFound, responseNoSuchList, nil)
+	case err != nil:
+		return err
+	}
+	// Placeholders and article options depend on the languages
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/?list=%d", list.ID))
+	return nil
+}
+
+// The name and languages of a list from the new list or list settings form
+func listFromForm(r *http.Request, nameField string) (List, error) {
+	list := List{Name: strings.TrimSpace(r.PostFormValue(nameField))}
+	if list.Name == "" || len([]rune(list.Name)) > 50 {
+		return List{}, newHTTPError(http.StatusBadRequest, responseBadListName, nil)
+	}
+
+	var err error
+	for _, field := range []struct {
+		name     string
+		dest     *string
+		fallback string
+	}{
+		{"source_language", &list.SourceLanguage, defaultSourceLanguage},
+		{"target_language", &list.TargetLanguage, defaultTargetLanguage},
+	} {
+		value := r.PostFormValue(field.name)
+		if value == "" {
+			value = field.fallback
+		}
+		if *field.dest, err = parseLanguage(value); err != nil {
+			return List{}, newHTTPError(http.StatusBadRequest, responseBadLanguage, err)
+		}
+	}
+	return list, nil
+}
+
+// Sends the form for moving or copying a word to another list
+func (c *Context) transferForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	lists, err := c.store.Lists(user_id)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Woord string
+		From  List
+		Lists []List
+	}{Woord: r.PathValue("woord"), From: list}
+	for _, other := range lists {
+		if other.ID != list.ID {
+			data.Lists = append(data.Lists, other)
+		}
+	}
+	return c.templates.Execute(w, "transfer-form.html", data)
+}
+
+func (c *Context) transferWord(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	from, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	to, err := strconv.Atoi(r.PostFormValue("to"))
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseNoSuchList, err)
+	}
+
+	word := r.PathValue("woord")
+	err = c.store.TransferWord(user_id, word, from.ID, to, r.PostFormValue("copy") != "", c.config.ImageQuota)
+	var exists *WordExistsError
+	switch {
+	case errors.As(err, &exists):
+		return newHTTPError(http.StatusConflict, fmt.Sprintf("<p>That list already has %s</p>", html.EscapeString(word)), err)
+	case err == errNotFound:
+		return newHTTPError(http.StatusNotFound, "<p>No such word or list</p>", nil)
+	case err == errQuotaExceeded:
+		msg := fmt.Sprintf("<p>Your pictures can take at most %d MB, delete some first</p>", c.config.ImageQuota>>20)
+		return newHTTPError(http.StatusRequestEntityTooLarge, msg, err)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user2", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (2, 'Van iemand anders')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("POST /lists", appHandler(c.createList))
+	router.Handle("POST /transfer/{woord}", appHandler(c.transferWord))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Without a list the user's first list is used, which is made on the spot
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=fiets&vertaling=bicycle").Code)
+
+	rr := do("POST", "/lists", "new_list=Hoofdstuk 2")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "/?list=3", rr.Header().Get("HX-Redirect"))
+	assert.Equal(t, http.StatusConflict, do("POST", "/lists", "new_list=Hoofdstuk 2").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/lists", "new_list=").Code)
+
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "list=3&woord=lopen").Code)
+	body := do("POST", "/", "list=3&search=").Body.String()
+	assert.Contains(t, body, "lopen")
+	assert.NotContains(t, body, "fiets")
+	assert.Contains(t, body, "woordenaantal: 1/1")
+	assert.Contains(t, body, "2 in alle lijsten")
+	assert.Contains(t, body, `<option value="3" selected title="Nederlands → English">Hoofdstuk 2 (1)</option>`)
+
+	require.Equal(t, http.StatusOK, do("POST", "/transfer/fiets?list=2", "to=3&copy=1").Code)
+	assert.Equal(t, http.StatusConflict, do("POST", "/transfer/fiets?list=2", "to=3").Code)
+	assert.Contains(t, do("POST", "/", "list=3&search=").Body.String(), "fiets")
+	assert.Contains(t, do("POST", "/", "list=2&search=").Body.String(), "fiets", "copying keeps the original")
+
+	assert.Equal(t, http.StatusNotFound, do("POST", "/", "list=1&search=").Code, "other users' lists can't be seen")
+	assert.Equal(t, http.StatusNotFound, do("POST", "/add/", "list=1&woord=kaas").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/transfer/fiets?list=2", "to=1").Code)
+
+	// Copying a picture over the quota is refused like uploading one
+	c.config.ImageQuota = 10
+	require.NoError(t, c.store.SaveImage(1, 3, "lopen", Image{Data: []byte("123456"), Thumbnail: []byte("12")}, c.config.ImageQuota))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do("POST", "/transfer/lopen?list=3", "to=2&copy=1").Code)
+	assert.NotContains(t, do("POST", "/", "list=2&search=").Body.String(), "lopen")
+}
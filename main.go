@@ -1,76 +1,176 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	_ "net/http/pprof"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type Context struct {
-	db *sql.DB
+	store        Store
+	config       Config
+	templates    *Templates
+	synthesizer  Synthesizer
+	dictionaries Dictionaries
+	translator   Translator
 }
 
-var DatabaseSchema = `
-CREATE TABLE IF NOT EXISTS users (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	username TEXT NOT NULL UNIQUE,
-	hashed_password TEXT NOT NULL
-);
-CREATE TABLE IF NOT EXISTS words (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	user_id INTEGER NOT NULL,
-	word TEXT NOT NULL,
-	word_type TEXT,
-	pronunciation TEXT,
-	translation TEXT,
-	FOREIGN KEY (user_id) REFERENCES users(id)
-);
-CREATE TABLE IF NOT EXISTS session_keys (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	user_id INTEGER NOT NULL,
-	session_key TEXT NOT NULL UNIQUE,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users(id)
-);`
-
 func main() {
-	port := ":8080"
-	router := http.NewServeMux()
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	cfg, rest, err := LoadConfig(args, os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	db, err := sql.Open("sqlite3", "./words.db")
+	switch command {
+	case "serve":
+		err = run(cfg)
+	case "migrate":
+		err = runMigrate(cfg, rest, os.Stdout)
+	case "import-wiktionary":
+		err = runImportWiktionary(cfg, rest, os.Stdout)
+	case "import-frequencies":
+		err = runImportFrequencies(cfg, rest, os.Stdout)
+	default:
+		err = fmt.Errorf("unknown command %q, use serve, migrate, import-wiktionary or import-frequencies", command)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+// Serves until SIGINT or SIGTERM, then lets in-flight requests finish and closes the database
+func run(cfg Config) error {
+	router := http.NewServeMux()
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		log.Println("Closing database")
+		if err := store.Close(); err != nil {
+			log.Printf("Failed to close database: %v", err)
+		}
+	}()
+
+	if err := migrateToLatest(store); err != nil {
+		return err
+	}
+
+	templatesFS, staticFS, err := assetFS(cfg)
+	if err != nil {
+		return err
+	}
+	templates, err := NewTemplates(templatesFS, cfg.Dev)
+	if err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+	static, err := staticHandler(staticFS, cfg.Dev)
+	if err != nil {
+		return fmt.Errorf("preparing static files: %w", err)
+	}
 
-	db.Exec(DatabaseSchema)
+	dictionaries, err := loadDictionaries(cfg.DictionaryDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	c := Context{
-		db: db,
+		store:        store,
+		config:       cfg,
+		templates:    templates,
+		synthesizer:  newSynthesizer(cfg),
+		dictionaries: dictionaries,
+		translator:   newTranslator(cfg),
 	}
 
-	fs := http.FileServer(http.Dir("./static"))
-	router.Handle("GET /static/", http.StripPrefix("/static/", fs))
+	router.Handle("GET /static/", http.StripPrefix("/static/", static))
 
-	router.HandleFunc("GET /", c.indexPage)
-	router.HandleFunc("POST /", c.search)
-	router.HandleFunc("POST /add/", c.add)
-	router.HandleFunc("DELETE /delete/{woord}", c.delete)
-	router.HandleFunc("DELETE /delete/", c.delete) // a way to delete an empty string word
-	router.HandleFunc("GET /login", c.loginPage)
-	router.HandleFunc("POST /login", c.loginForm)
-	router.HandleFunc("GET /logout", c.logout)
+	router.Handle("GET /", appHandler(c.indexPage))
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("DELETE /delete/{woord}", appHandler(c.delete))
+	router.Handle("DELETE /delete/", appHandler(c.delete)) // a way to delete an empty string word
+	router.Handle("GET /suggest", appHandler(c.suggest))
+	router.Handle("GET /suggest/translation", appHandler(c.suggestTranslation))
+	router.Handle("GET /conjugation/{woord}", appHandler(c.conjugationForm))
+	router.Handle("POST /conjugation/{woord}", appHandler(c.saveConjugation))
+	router.Handle("GET /tags/{woord}", appHandler(c.tagsForm))
+	router.Handle("POST /tags/{woord}", appHandler(c.saveTags))
+	router.Handle("POST /lists", appHandler(c.createList))
+	router.Handle("POST /lists/{id}", appHandler(c.updateList))
+	router.Handle("GET /examples/{woord}", appHandler(c.exampleForm))
+	router.Handle("POST /examples/{woord}", appHandler(c.addExample))
+	router.Handle("DELETE /examples/{woord}/{id}", appHandler(c.deleteExample))
+	router.Handle("GET /recording/{woord}", appHandler(c.serveRecording))
+	router.Handle("GET /recording/{woord}/form", appHandler(c.recordingForm))
+	router.Handle("POST /recording/{woord}", appHandler(c.uploadRecording))
+	router.Handle("DELETE /recording/{woord}", appHandler(c.deleteRecording))
+	router.Handle("GET /image/{woord}", appHandler(c.serveImage))
+	router.Handle("GET /image/{woord}/form", appHandler(c.imageForm))
+	router.Handle("POST /image/{woord}", appHandler(c.uploadImage))
+	router.Handle("DELETE /image/{woord}", appHandler(c.deleteImage))
+	router.Handle("GET /speech/{woord}", appHandler(c.serveSpeech))
+	router.Handle("GET /text", appHandler(c.textForm))
+	router.Handle("POST /text", appHandler(c.mineText))
+	router.Handle("POST /text/file", appHandler(c.mineFile))
+	router.Handle("GET /kindle", appHandler(c.kindleForm))
+	router.Handle("POST /kindle", appHandler(c.importKindle))
+	router.Handle("GET /frequent", appHandler(c.frequentWords))
+	router.Handle("GET /review", appHandler(c.review))
+	router.Handle("POST /review", appHandler(c.checkReview))
+	router.Handle("GET /transfer/{woord}", appHandler(c.transferForm))
+	router.Handle("POST /transfer/{woord}", appHandler(c.transferWord))
+	router.Handle("GET /login", appHandler(c.loginPage))
+	router.Handle("POST /login", appHandler(c.loginForm))
+	router.Handle("GET /logout", appHandler(c.logout))
 
 	wrappedRouter := NewLogger(router)
 
 	server := http.Server{
-		Addr:    port,
-		Handler: wrappedRouter,
+		Addr:              cfg.Addr,
+		Handler:           wrappedRouter,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server http://localhost%s", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
 	}
+	stop() // a second Ctrl+C kills the process right away
 
-	log.Printf("Starting server http://localhost%s", server.Addr)
-	server.ListenAndServe()
+	log.Printf("Shutting down, waiting up to %v for requests to finish", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down server: %w", err)
+	}
+	return nil
 }
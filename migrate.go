@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change, read from a pair of files like 0002_words_user_index.up.sql and .down.sql.
+// Every database dialect has its own directory of migrations, with the same versions in each.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Reads every migration in the root of fsys and returns them ordered by version.
+// Each version needs exactly one up and one down file.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file in migrations: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations, keeping track of them in the schema_migrations table.
+// Every migration runs in its own transaction together with its schema_migrations bookkeeping.
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, d dialect) (*Migrator, error) {
+	fsys, err := fs.Sub(migrationFiles, d.migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	return &Migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]string{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		at, applied := appliedAt[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: applied, AppliedAt: at}
+	}
+	return statuses, nil
+}
+
+// Applies pending migrations up to and including version target, or all of them when target is 0.
+// Returns the migrations that were applied.
+func (m *Migrator) Up(target int) ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		if target != 0 && status.Version > target {
+			break
+		}
+		err := m.inTx(status.Migration.Up, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", status.Version, status.Name)
+		if err != nil {
+			return applied, fmt.Errorf("applying migration %d_%s: %w", status.Version, status.Name, err)
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+// Rolls back the last steps applied migrations, newest first. Returns the migrations that were rolled back.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		err := m.inTx(status.Migration.Down, "DELETE FROM schema_migrations WHERE version = ?", status.Version)
+		if err != nil {
+			return rolledBack, fmt.Errorf("rolling back migration %d_%s: %w", status.Version, status.Name, err)
+		}
+		rolledBack = append(rolledBack, status.Migration)
+	}
+	return rolledBack, nil
+}
+
+func (m *Migrator) inTx(migrationSQL, bookkeepingSQL string, args ...any) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if m.dialect.pauseForeignKeys {
+		// SQLite ignores the pragma inside a transaction, so it is set on the connection around it
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrationSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.dialect.rebind(bookkeepingSQL), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func printMigrationStatus(w io.Writer, statuses []MigrationStatus) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	tw.Flush()
+}
+
+// Brings the database schema up to date, used when the server starts
+func migrateToLatest(store *sqlStore) error {
+	migrator, err := NewMigrator(store.db, store.dialect)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(0)
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	return err
+}
+
+// Handles `wordsearch migrate status`, `migrate up [version]` and `migrate down [steps]`
+func runMigrate(cfg Config, args []string, out io.Writer) error {
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	migrator, err := NewMigrator(store.db, store.dialect)
+	if err != nil {
+		return err
+	}
+
+	subcommand := "status"
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+	number := 0
+	if len(args) > 1 {
+		number, err = strconv.Atoi(args[1])
+		if err != nil || number < 0 {
+			return fmt.Errorf("migrate %s: expected a non-negative number, got %q", subcommand, args[1])
+		}
+	}
+
+	var changed []Migration
+	switch subcommand {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(out, statuses)
+		return nil
+	case "up":
+		changed, err = migrator.Up(number)
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+		changed, err = migrator.Down(number)
+	default:
+		return fmt.Errorf("unknown migrate command %q, use status, up or down", subcommand)
+	}
+
+	for _, migration := range changed {
+		fmt.Fprintf(out, "%s %04d_%s\n", subcommand, migration.Version, migration.Name)
+	}
+	if len(changed) == 0 && err == nil {
+		fmt.Fprintln(out, "nothing to do")
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openEmptyTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open(sqliteDialect.driver, ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(db *sql.DB, name string) bool {
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&count)
+	return count == 1
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("up 2")},
+		"0002_second.down.sql": {Data: []byte("down 2")},
+		"0001_first.up.sql":    {Data: []byte("up 1")},
+		"0001_first.down.sql":  {Data: []byte("down 1")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+	}, migrations)
+
+	_, err = loadMigrations(fstest.MapFS{"0001_first.up.sql": {Data: []byte("up 1")}})
+	assert.Error(t, err, "a migration without a down file")
+
+	_, err = loadMigrations(fstest.MapFS{"notes.txt": {Data: []byte("hi")}})
+	assert.Error(t, err, "a file that isn't a migration")
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db := openEmptyTestDB(t)
+	migrator, err := NewMigrator(db, sqliteDialect)
+	assert.NoError(t, err)
+
+	applied, err := migrator.Up(1)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.True(t, tableExists(db, "words"))
+	assert.False(t, tableExists(db, "words_user_id"))
+
+	applied, err = migrator.Up(0)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrator.migrations)-1, len(applied))
+	assert.True(t, tableExists(db, "words_user_id"))
+
+	applied, err = migrator.Up(0)
+	assert.NoError(t, err)
+	assert.Empty(t, applied, "running up twice does nothing")
+
+	rolledBack, err := migrator.Down(len(migrator.migrations))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(migrator.migrations))
+	assert.False(t, tableExists(db, "words"))
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+}
+
+func TestMigratorAdoptsExistingDatabase(t *testing.T) {
+	db := openEmptyTestDB(t)
+	// What main used to create before there were migrations
+	_, err := db.Exec(`
+	CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT NOT NULL UNIQUE, hashed_password TEXT NOT NULL);
+	CREATE TABLE words (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, word TEXT NOT NULL, word_type TEXT, pronunciation TEXT, translation TEXT);
+	CREATE TABLE session_keys (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, session_key TEXT NOT NULL UNIQUE, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	INSERT INTO users (username, hashed_password) VALUES ('old', 'hash');
+	INSERT INTO words (user_id, word) VALUES (1, 'oud');`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, migrateToLatest(&sqlStore{db: db, dialect: sqliteDialect}))
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM words").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestRunMigrateStatus(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DatabasePath = t.TempDir() + "/words.db"
+
+	var out bytes.Buffer
+	assert.NoError(t, runMigrate(cfg, []string{"up", "1"}, &out))
+	assert.Contains(t, out.String(), "up 0001_initial_schema")
+
+	out.Reset()
+	assert.NoError(t, runMigrate(cfg, nil, &out))
+	assert.Regexp(t, `0002\s+words_user_index\s+pending`, out.String())
+
+	assert.Error(t, runMigrate(cfg, []string{"sideways"}, &out))
+}
+
+func TestMigrationKeepsDuplicatesAsSenses(t *testing.T) {
+	db := openEmptyTestDB(t)
+	migrator, err := NewMigrator(db, sqliteDialect)
+	assert.NoError(t, err)
+	_, err = migrator.Up(2)
+	assert.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES ('anna', 'hash'), ('bob', 'hash')")
+	db.Exec("INSERT INTO words (user_id, word, translation) VALUES (1, 'bank', 'couch'), (1, 'bank', 'bank'), (2, 'bank', 'bench')")
+	_, err = migrator.Up(3)
+	assert.NoError(t, err)
+
+	rows, err := db.Query("SELECT user_id, sense FROM words ORDER BY id")
+	assert.NoError(t, err)
+	defer rows.Close()
+	var got [][2]int
+	for rows.Next() {
+		var userID, sense int
+		rows.Scan(&userID, &sense)
+		got = append(got, [2]int{userID, sense})
+	}
+	assert.Equal(t, [][2]int{{1, 1}, {1, 2}, {2, 1}}, got)
+}
+
+func TestMigrationFoldsSensesIntoOneWord(t *testing.T) {
+	db := openEmptyTestDB(t)
+	migrator, err := NewMigrator(db, sqliteDialect)
+	assert.NoError(t, err)
+	_, err = migrator.Up(3)
+	assert.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES ('anna', 'hash')")
+	db.Exec(`INSERT INTO words (user_id, word, sense, word_type, pronunciation, translation) VALUES
+		(1, 'bank', 1, 'zn', 'bɑŋk', 'couch'),
+		(1, 'bank', 2, 'zn', 'bɑŋk', 'bank'),
+		(1, 'fiets', 1, 'zn', '', 'bicycle')`)
+	_, err = migrator.Up(4)
+	assert.NoError(t, err)
+
+	var words, senses int
+	db.QueryRow("SELECT COUNT(*) FROM words").Scan(&words)
+	db.QueryRow("SELECT COUNT(*) FROM senses JOIN words ON words.id = senses.word_id WHERE word = 'bank'").Scan(&senses)
+	assert.Equal(t, 2, words)
+	assert.Equal(t, 2, senses, "both rows of bank became senses of one word")
+
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM words WHERE word = 'bank' AND translation IN ('couch', 'bank')").Scan(&count)
+	assert.Equal(t, 2, count, "rolling back splits the senses into rows again")
+}
+
+func TestMigrationPutsWordsInAList(t *testing.T) {
+	db := openEmptyTestDB(t)
+	migrator, err := NewMigrator(db, sqliteDialect)
+	assert.NoError(t, err)
+	_, err = migrator.Up(7)
+	assert.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES ('anna', ''), ('bob', '')")
+	db.Exec("INSERT INTO words (user_id, word) VALUES (1, 'fiets'), (2, 'fiets')")
+	_, err = migrator.Up(8)
+	assert.NoError(t, err)
+
+	var lists, orphans int
+	db.QueryRow("SELECT COUNT(*) FROM lists WHERE name = 'Mijn woorden'").Scan(&lists)
+	db.QueryRow("SELECT COUNT(*) FROM words JOIN lists ON lists.id = words.list_id WHERE lists.user_id <> words.user_id").Scan(&orphans)
+	assert.Equal(t, 2, lists, "every user gets a first list")
+	assert.Equal(t, 0, orphans, "words end up in their owner's list")
+
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	assert.False(t, tableExists(db, "lists"))
+}
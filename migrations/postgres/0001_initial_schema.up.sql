@@ -0,0 +1,19 @@
+CREATE TABLE users (
+	id SERIAL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	hashed_password TEXT NOT NULL
+);
+CREATE TABLE words (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	word TEXT NOT NULL,
+	word_type TEXT,
+	pronunciation TEXT,
+	translation TEXT
+);
+CREATE TABLE session_keys (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	session_key TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
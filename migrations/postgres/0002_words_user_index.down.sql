@@ -0,0 +1 @@
+DROP INDEX words_user_id;
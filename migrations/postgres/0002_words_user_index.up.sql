@@ -0,0 +1,2 @@
+-- Every table render filters and counts by user_id
+CREATE INDEX words_user_id ON words (user_id);
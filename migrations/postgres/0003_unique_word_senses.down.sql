@@ -0,0 +1,2 @@
+DROP INDEX words_user_id_word_sense;
+ALTER TABLE words DROP COLUMN sense;
@@ -0,0 +1,7 @@
+-- Duplicates that piled up before this migration are kept as separate senses of the word, numbered in the order they were added
+ALTER TABLE words ADD COLUMN sense INTEGER NOT NULL DEFAULT 1;
+UPDATE words SET sense = (
+	SELECT COUNT(*) FROM words AS earlier
+	WHERE earlier.user_id = words.user_id AND earlier.word = words.word AND earlier.id <= words.id
+);
+CREATE UNIQUE INDEX words_user_id_word_sense ON words (user_id, word, sense);
@@ -0,0 +1,16 @@
+-- Every sense becomes a words row again, notes are lost
+DROP INDEX words_user_id_word;
+ALTER TABLE words ADD COLUMN word_type TEXT;
+ALTER TABLE words ADD COLUMN translation TEXT;
+ALTER TABLE words ADD COLUMN sense INTEGER NOT NULL DEFAULT 1;
+INSERT INTO words (user_id, word, pronunciation, word_type, translation, sense)
+SELECT w.user_id, w.word, w.pronunciation, s.part_of_speech, s.translation, s.position
+FROM senses AS s JOIN words AS w ON w.id = s.word_id
+WHERE s.position > (SELECT MIN(first.position) FROM senses AS first WHERE first.word_id = s.word_id);
+UPDATE words SET
+	word_type = (SELECT s.part_of_speech FROM senses AS s WHERE s.word_id = words.id ORDER BY s.position LIMIT 1),
+	translation = (SELECT s.translation FROM senses AS s WHERE s.word_id = words.id ORDER BY s.position LIMIT 1),
+	sense = (SELECT MIN(s.position) FROM senses AS s WHERE s.word_id = words.id)
+WHERE id IN (SELECT word_id FROM senses);
+DROP TABLE senses;
+CREATE UNIQUE INDEX words_user_id_word_sense ON words (user_id, word, sense);
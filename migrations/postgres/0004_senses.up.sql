@@ -0,0 +1,24 @@
+-- A word now has one row in words and one row per meaning in senses.
+-- Rows that were separate senses of the same word are folded into the oldest row for that word.
+CREATE TABLE senses (
+	id SERIAL PRIMARY KEY,
+	word_id INTEGER NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	part_of_speech TEXT NOT NULL DEFAULT '',
+	translation TEXT NOT NULL DEFAULT '',
+	notes TEXT NOT NULL DEFAULT '',
+	UNIQUE (word_id, position)
+);
+INSERT INTO senses (word_id, position, part_of_speech, translation)
+SELECT
+	(SELECT MIN(first.id) FROM words AS first WHERE first.user_id = words.user_id AND first.word = words.word),
+	sense,
+	COALESCE(word_type, ''),
+	COALESCE(translation, '')
+FROM words;
+DELETE FROM words WHERE id NOT IN (SELECT word_id FROM senses);
+DROP INDEX words_user_id_word_sense;
+ALTER TABLE words DROP COLUMN sense;
+ALTER TABLE words DROP COLUMN word_type;
+ALTER TABLE words DROP COLUMN translation;
+CREATE UNIQUE INDEX words_user_id_word ON words (user_id, word);
@@ -0,0 +1,4 @@
+DROP INDEX words_user_id_article;
+ALTER TABLE words DROP COLUMN diminutive;
+ALTER TABLE words DROP COLUMN plural;
+ALTER TABLE words DROP COLUMN article;
@@ -0,0 +1,4 @@
+ALTER TABLE words ADD COLUMN article TEXT NOT NULL DEFAULT '';
+ALTER TABLE words ADD COLUMN plural TEXT NOT NULL DEFAULT '';
+ALTER TABLE words ADD COLUMN diminutive TEXT NOT NULL DEFAULT '';
+CREATE INDEX words_user_id_article ON words (user_id, article);
@@ -0,0 +1,12 @@
+-- At most one conjugation table per word, only for verbs
+CREATE TABLE conjugations (
+	word_id INTEGER PRIMARY KEY REFERENCES words(id) ON DELETE CASCADE,
+	present_ik TEXT NOT NULL DEFAULT '',
+	present_jij TEXT NOT NULL DEFAULT '',
+	present_hij TEXT NOT NULL DEFAULT '',
+	present_plural TEXT NOT NULL DEFAULT '',
+	past_singular TEXT NOT NULL DEFAULT '',
+	past_plural TEXT NOT NULL DEFAULT '',
+	past_participle TEXT NOT NULL DEFAULT '',
+	auxiliary TEXT NOT NULL DEFAULT ''
+);
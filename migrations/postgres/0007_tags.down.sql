@@ -0,0 +1,3 @@
+DROP INDEX word_tags_tag_id;
+DROP TABLE word_tags;
+DROP TABLE tags;
@@ -0,0 +1,13 @@
+-- Tags group words by chapter, source or theme. They belong to a user, a word can have any number of them.
+CREATE TABLE tags (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	UNIQUE (user_id, name)
+);
+CREATE TABLE word_tags (
+	word_id INTEGER NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (word_id, tag_id)
+);
+CREATE INDEX word_tags_tag_id ON word_tags (tag_id);
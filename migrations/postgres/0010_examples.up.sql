@@ -0,0 +1,8 @@
+-- Example sentences that show a word in use, drilled as cloze exercises
+CREATE TABLE examples (
+	id SERIAL PRIMARY KEY,
+	word_id INTEGER NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+	sentence TEXT NOT NULL,
+	translation TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX examples_word_id ON examples (word_id);
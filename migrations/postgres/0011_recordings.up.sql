@@ -0,0 +1,6 @@
+-- One pronunciation recording per word, the content type is the one sniffed from the data
+CREATE TABLE recordings (
+	word_id INTEGER PRIMARY KEY REFERENCES words(id) ON DELETE CASCADE,
+	content_type TEXT NOT NULL,
+	data BYTEA NOT NULL
+);
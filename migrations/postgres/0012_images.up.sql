@@ -0,0 +1,6 @@
+-- One picture per word, re-encoded as JPEG with a thumbnail for the table
+CREATE TABLE images (
+	word_id INTEGER PRIMARY KEY REFERENCES words(id) ON DELETE CASCADE,
+	data BYTEA NOT NULL,
+	thumbnail BYTEA NOT NULL
+);
@@ -0,0 +1,12 @@
+-- Words from a Wiktionary dump, shared by all users, looked up while adding words
+CREATE TABLE reference_words (
+	id SERIAL PRIMARY KEY,
+	language TEXT NOT NULL,
+	gloss_language TEXT NOT NULL,
+	word TEXT NOT NULL,
+	word_key TEXT NOT NULL,
+	part_of_speech TEXT NOT NULL DEFAULT '',
+	ipa TEXT NOT NULL DEFAULT '',
+	glosses TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX reference_words_lookup ON reference_words (language, word_key);
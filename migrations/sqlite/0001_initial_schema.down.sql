@@ -0,0 +1,3 @@
+DROP TABLE session_keys;
+DROP TABLE words;
+DROP TABLE users;
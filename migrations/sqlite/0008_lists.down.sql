@@ -0,0 +1,9 @@
+-- Copies of a word in other lists are dropped, the oldest one stays
+DELETE FROM words WHERE id NOT IN (SELECT MIN(id) FROM words GROUP BY user_id, word);
+DELETE FROM senses WHERE word_id NOT IN (SELECT id FROM words);
+DELETE FROM conjugations WHERE word_id NOT IN (SELECT id FROM words);
+DELETE FROM word_tags WHERE word_id NOT IN (SELECT id FROM words);
+DROP INDEX words_list_id_word;
+CREATE UNIQUE INDEX words_user_id_word ON words (user_id, word);
+ALTER TABLE words DROP COLUMN list_id;
+DROP TABLE lists;
@@ -0,0 +1,14 @@
+-- Words live in named lists (decks) owned by a user. Every user gets a first list holding the words they already have.
+-- The same word can be in several lists, so words are unique per list instead of per user.
+CREATE TABLE lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	UNIQUE (user_id, name)
+);
+INSERT INTO lists (user_id, name) SELECT id, 'Mijn woorden' FROM users;
+-- No REFERENCES here: SQLite can't drop a column with a foreign key, which the down migration needs
+ALTER TABLE words ADD COLUMN list_id INTEGER;
+UPDATE words SET list_id = (SELECT id FROM lists WHERE lists.user_id = words.user_id);
+DROP INDEX words_user_id_word;
+CREATE UNIQUE INDEX words_list_id_word ON words (list_id, word);
@@ -0,0 +1,2 @@
+ALTER TABLE lists DROP COLUMN target_language;
+ALTER TABLE lists DROP COLUMN source_language;
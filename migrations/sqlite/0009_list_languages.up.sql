@@ -0,0 +1,3 @@
+-- BCP-47 tags of the language a list's words are in and the language they are translated to
+ALTER TABLE lists ADD COLUMN source_language TEXT NOT NULL DEFAULT 'nl';
+ALTER TABLE lists ADD COLUMN target_language TEXT NOT NULL DEFAULT 'en';
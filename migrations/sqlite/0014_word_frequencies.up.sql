@@ -0,0 +1,10 @@
+-- Frequency lists like SUBTLEX-NL, shared by all users. Rank 1 is the most frequent word of the language.
+CREATE TABLE word_frequencies (
+	language TEXT NOT NULL,
+	word_key TEXT NOT NULL,
+	word TEXT NOT NULL,
+	frequency INTEGER NOT NULL,
+	rank INTEGER NOT NULL,
+	PRIMARY KEY (language, word_key)
+);
+CREATE INDEX word_frequencies_rank ON word_frequencies (language, rank);
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Svuvi/wordsearch/dictionary"
+	"github.com/Svuvi/wordsearch/dutch"
+	"github.com/Svuvi/wordsearch/extract"
+)
+
+const (
+	// Longest text that can be pasted, in bytes, a long news article is a tenth of it
+	maxMinedText = 200_000
+	// Longest text taken from an uploaded file, in bytes, a thick novel is about 2 MB
+	maxExtractedText = 8 << 20
+	// Unknown words shown at once, a book has thousands
+	maxMinedWords = 500
+)
+
+var (
+	responseTextTooLong     = `<p>Texts can be at most 200 kB, paste a part of it</p>`
+	responseTextFileTooLong = `<p>The file is too large, upload a smaller one</p>`
+	responseBadTextFile     = `<p>Upload SRT or WebVTT subtitles or an EPUB book</p>`
+)
+
+// Dutch words that lose their first letters to an apostrophe: 't, 'n, 'k
+var dutchElisions = map[string]string{
+	"t": "het",
+	"n": "een",
+	"k": "ik",
+	"m": "hem",
+	"r": "haar",
+	"s": "des",
+}
+
+// minedWord is a word from a pasted text that isn't saved in the list
+type minedWord struct {
+	Woord string
+	Count int
+	Forms []string // how it was written in the text, when that isn't Woord
+	Zin   string   // the first sentence it appears in that is short enough to be an example
+	Entry dictionary.Entry
+}
+
+// Translations of the dictionary entry, as they are put in the add form
+func (m minedWord) Vertaling() string {
+	return strings.Join(m.Entry.Translations, "; ")
+}
+
+// A word as it appears in a text, lowercased, with whether it was capitalized in the middle of a sentence
+type textWord struct {
+	text string
+	name bool
+}
+
+// Splits a text into lowercase words. Elided Dutch words become whole again and numbers are left out.
+func textWords(text string, list List) []textWord {
+	var words []textWord
+	for _, t := range tokenize(text) {
+		word := strings.ToLower(t.text)
+		if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		elided := strings.HasSuffix(text[:t.start], "'") || strings.HasSuffix(text[:t.start], "’")
+		if full, ok := dutchElisions[word]; ok && elided && list.IsDutch() {
+			word = full
+		}
+		if utf8.RuneCountInString(word) < 2 {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(t.text)
+		words = append(words, textWord{text: word, name: unicode.IsUpper(first) && !sentenceStart(text[:t.start])})
+	}
+	return words
+}
+
+// Splits a text into sentences at line ends and after full stops, question and exclamation marks.
+// Abbreviations like "dhr." end a sentence too.
+func splitSentences(text string) []string {
+	var sentences []string
+	add := func(sentence string) {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+	start := 0
+	for i, r := range text {
+		switch {
+		case r == '\n':
+			add(text[start:i])
+			start = i + 1
+		case unicode.IsSpace(r) && i > start:
+			// A quote or bracket can close the sentence after its full stop
+			last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(text[start:i], `"'”’»)`))
+			if strings.ContainsRune(".!?…", last) {
+				add(text[start:i])
+				start = i
+			}
+		}
+	}
+	add(text[start:])
+	return sentences
+}
+
+// Whether a word after this text starts a sentence, so its capital says nothing
+func sentenceStart(before string) bool {
+	before = strings.TrimRightFunc(before, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'“‘(-–`, r)
+	})
+	if before == "" {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(before)
+	return strings.ContainsRune(".!?:…", last)
+}
+
+// Every form of the saved words in the list, lowercased
+func knownForms(words []Word, list List) map[string]bool {
+	known := map[string]bool{}
+	for _, word := range words {
+		for _, form := range clozeForms(word, list) {
+			if form != "" {
+				known[strings.ToLower(form)] = true
+			}
+		}
+	}
+	return known
+}
+
+// Lists the words of the text that aren't saved in the list, most frequent first, and counts the words of the text.
+// A form counts as saved when its lemma is, and unknown forms are counted under the lemma a dictionary knows.
+// Words that are only ever capitalized in the middle of a sentence are taken to be names and left out.
+func (c *Context) mine(text string, list List, saved []Word) ([]minedWord, int, error) {
+	known := knownForms(saved, list)
+	type textSentence struct {
+		text  string
+		words []textWord
+	}
+	var sentences []textSentence
+	lemmas := map[string][]string{} // candidates for every unknown form
+	var lookups []string            // unknown forms and their lemmas, the reference is asked about all of them at once
+	total := 0
+	for _, sentence := range splitSentences(normalizeText(text, list.SourceLanguage)) {
+		words := textWords(sentence, list)
+		total += len(words)
+		sentences = append(sentences, textSentence{sentence, words})
+		for _, word := range words {
+			if _, ok := lemmas[word.text]; ok || known[word.text] {
+				continue
+			}
+			var candidates []string
+			if list.IsDutch() {
+				candidates = dutch.Lemmas(word.text)
+			}
+			lemmas[word.text] = candidates
+			lookups = append(append(lookups, word.text), candidates...)
+		}
+	}
+	references, err := c.store.ReferenceEntriesOf(baseLanguage(list.SourceLanguage), lookups)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byWord := map[string]*minedWord{}
+	onlyNames := map[string]bool{}
+	var order []*minedWord
+	for _, sentence := range sentences {
+		for _, word := range sentence.words {
+			if known[word.text] {
+				continue
+			}
+			if slices.ContainsFunc(lemmas[word.text], func(lemma string) bool { return known[lemma] }) {
+				continue
+			}
+
+			mined, ok := byWord[word.text]
+			if !ok {
+				entry := c.withReference(list, word.text, "", references[word.text])
+				lemma := word.text
+				// A form the dictionaries don't have is counted under a lemma they do have
+				for _, candidate := range lemmas[word.text] {
+					if entry.Headword != "" {
+						break
+					}
+					if candidateEntry := c.withReference(list, candidate, "", references[candidate]); candidateEntry.Headword != "" {
+						lemma, entry = candidate, candidateEntry
+					}
+				}
+
+				if mined, ok = byWord[lemma]; !ok {
+					mined = &minedWord{Woord: lemma, Entry: entry}
+					byWord[lemma] = mined
+					order = append(order, mined)
+					onlyNames[lemma] = true
+				}
+				byWord[word.text] = mined
+				if word.text != lemma {
+					mined.Forms = append(mined.Forms, word.text)
+				}
+			}
+			mined.Count++
+			if !word.name {
+				onlyNames[mined.Woord] = false
+			}
+			if mined.Zin == "" && utf8.RuneCountInString(sentence.text) <= maxExampleLength {
+				mined.Zin = sentence.text
+			}
+		}
+	}
+
+	var unknown []minedWord
+	for _, mined := range order {
+		if !onlyNames[mined.Woord] {
+			unknown = append(unknown, *mined)
+		}
+	}
+	sort.SliceStable(unknown, func(i, j int) bool { return unknown[i].Count > unknown[j].Count })
+	return unknown, total, nil
+}
+
+type textTmplData struct {
+	List         List
+	Text         string
+	File         string // name of the uploaded file the words come from
+	Mined        bool
+	Unknown      []minedWord // the most frequent ones when there are more than maxMinedWords
+	UnknownTotal int
+	Total        int // words in the text
+}
+
+// Sends the form for pasting a text
+func (c *Context) textForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	return c.templates.Execute(w, "text-form.html", textTmplData{List: list})
+}
+
+// Finds the words of a pasted text that aren't saved in the list yet
+func (c *Context) mineText(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	// Form encoding can make the text up to three times as long
+	r.Body = http.MaxBytesReader(w, r.Body, 4*maxMinedText)
+	if err := r.ParseForm(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newHTTPError(http.StatusRequestEntityTooLarge, responseTextTooLong, err)
+		}
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	text := r.PostFormValue("text")
+	if len(text) > maxMinedText {
+		return newHTTPError(http.StatusRequestEntityTooLarge, responseTextTooLong, nil)
+	}
+	return c.showUnknownWords(w, user_id, textTmplData{List: list, Text: text}, text)
+}
+
+// Finds the words of uploaded subtitles or an EPUB book that aren't saved in the list yet
+func (c *Context) mineFile(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	// Room for the other fields and the multipart boundaries
+	limit := c.config.MaxTextFileSize
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newHTTPError(http.StatusRequestEntityTooLarge, responseTextFileTooLong, err)
+		}
+		return newHTTPError(http.StatusBadRequest, responseBadTextFile, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadTextFile, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return newHTTPError(http.StatusRequestEntityTooLarge, responseTextFileTooLong, nil)
+	}
+
+	text, err := extract.Text(header.Filename, data)
+	if errors.Is(err, extract.ErrUnsupported) {
+		return newHTTPError(http.StatusUnsupportedMediaType, responseBadTextFile, err)
+	}
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadTextFile, err)
+	}
+	if len(text) > maxExtractedText {
+		return newHTTPError(http.StatusRequestEntityTooLarge, responseTextFileTooLong, nil)
+	}
+	return c.showUnknownWords(w, user_id, textTmplData{List: list, File: header.Filename}, text)
+}
+
+// Sends the text form with the words of text that aren't saved in the list of data
+func (c *Context) showUnknownWords(w http.ResponseWriter, user_id int, data textTmplData, text string) error {
+	saved, err := c.store.SearchWords(user_id, WordFilter{List: data.List.ID})
+	if err != nil {
+		return err
+	}
+	unknown, total, err := c.mine(text, data.List, saved)
+	if err != nil {
+		return err
+	}
+	data.Mined, data.Total = true, total
+	data.Unknown, data.UnknownTotal = unknown[:min(len(unknown), maxMinedWords)], len(unknown)
+	return c.templates.Execute(w, "text-form.html", data)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextWords(t *testing.T) {
+	dutch := List{SourceLanguage: "nl", TargetLanguage: "en"}
+	words := textWords("Hij pakt z'n fiets. 't is 3 uur, zegt Rutte: “Kom!” 's Avonds", dutch)
+	assert.Equal(t, []textWord{
+		{text: "hij"}, {text: "pakt"}, {text: "z'n"}, {text: "fiets"},
+		{text: "het"}, {text: "is"}, {text: "uur"}, {text: "zegt"},
+		{text: "rutte", name: true}, {text: "kom"}, {text: "des"}, {text: "avonds", name: true},
+	}, words)
+
+	german := List{SourceLanguage: "de", TargetLanguage: "en"}
+	assert.Equal(t, []textWord{{text: "geht's"}}, textWords("Geht's", german), "elisions are Dutch")
+}
+
+func TestSplitSentences(t *testing.T) {
+	assert.Equal(t, []string{"Hij zegt: “Kom!”", "Wat?", "Dat is 3.5 km…", "Nieuwe regel", "en meer"},
+		splitSentences("Hij zegt: “Kom!” Wat? Dat is 3.5 km… Nieuwe regel\n\n  en meer"))
+}
+
+func TestMineText(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	_, err = c.store.ImportReference("nl", "en", readWiktextract(strings.NewReader(testDump), "nl"))
+	require.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("GET /text", appHandler(c.textForm))
+	router.Handle("POST /text", appHandler(c.mineText))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Contains(t, do("GET", "/text", "").Body.String(), `<textarea name="text" lang="nl"`)
+
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=fiets&lidwoord=de").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=werken&woordsoort=ww").Code)
+
+	text := "De fietsen staan bij het huis. Wij werkten in Amsterdam. Huizen zijn duur, z'n huizen ook."
+	rr := do("POST", "/text", "text="+url.QueryEscape(text))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+
+	assert.Contains(t, body, "16 woorden, 11 nog niet in Mijn woorden")
+	assert.NotContains(t, body, "<b lang=\"nl\">fietsen</b>", "the plural of a saved noun is known")
+	assert.NotContains(t, body, `<b lang="nl">werkten</b>`, "so is the conjugation of a saved verb")
+	assert.NotContains(t, body, `<b lang="nl">amsterdam</b>`, "names are left out")
+	assert.Contains(t, body, `<b lang="nl">huis</b> <span class="suggestion" title="times in the text">3×</span>`, "forms are counted under the lemma Wiktionary knows, and it is the most frequent")
+	assert.Less(t, strings.Index(body, "<b lang=\"nl\">huis</b>"), strings.Index(body, "<b lang=\"nl\">de</b>"))
+	assert.Contains(t, body, "(huizen)")
+	assert.Contains(t, body, `<b lang="nl">z&#39;n</b>`)
+	assert.Contains(t, body, `<input type="hidden" name="vertaling" value="house; home; household">`)
+	assert.Contains(t, body, `<input type="hidden" name="uitspraak" value="ɦœys">`)
+	assert.Contains(t, body, `<p class="example" lang="nl">Wij werkten in Amsterdam.</p>`, "words are shown with the first sentence they are in")
+
+	// One click adds the word with what Wiktionary knows about it
+	require.Equal(t, http.StatusOK, do("POST", "/add/?list=1", "woord=huis&vertaling=house&uitspraak=ɦœys&woordsoort=noun").Code)
+	body = do("POST", "/text", "text="+url.QueryEscape(text)).Body.String()
+	assert.Contains(t, body, "16 woorden, 10 nog niet in Mijn woorden")
+	assert.NotContains(t, body, "<b lang=\"nl\">huis</b>")
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do("POST", "/text", "text="+strings.Repeat("a", maxMinedText+1)).Code)
+}
+
+func TestMineFile(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	c.config.MaxTextFileSize = 1 << 10
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("POST /text/file", appHandler(c.mineFile))
+	upload := func(name, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", name)
+		fw.Write([]byte(content))
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/text/file?list=1", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	srt := "1\n00:00:01,000 --> 00:00:02,000\n<i>De kat slaapt</i>\n\n2\n00:00:03,000 --> 00:00:04,000\nop de bank.\n"
+	rr := upload("aflevering.srt", srt)
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "aflevering.srt: 6 woorden, 5 nog niet in Mijn woorden")
+	assert.Contains(t, body, `<p class="example" lang="nl">De kat slaapt op de bank.</p>`, "cues are joined into sentences")
+	assert.Contains(t, body, `<input type="hidden" name="voorbeeld" value="De kat slaapt op de bank.">`)
+
+	// Adding the word saves the sentence as its example
+	req, _ := http.NewRequest("POST", "/add/?list=1", strings.NewReader("woord=kat&voorbeeld="+url.QueryEscape("De kat slaapt op de bank.")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	saved, err := c.store.Word(1, 1, "kat")
+	require.NoError(t, err)
+	require.Len(t, saved.Voorbeelden, 1)
+	assert.Equal(t, "De kat slaapt op de bank.", saved.Voorbeelden[0].Zin)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload("boek.pdf", "%PDF").Code)
+	assert.Equal(t, http.StatusBadRequest, upload("boek.epub", "not a zip").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("film.srt", strings.Repeat("a", 2<<10)).Code)
+}
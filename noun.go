@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Dutch nouns take de or het, a few take both depending on meaning or region
+const (
+	articleDe    = "de"
+	articleHet   = "het"
+	articleDeHet = "de/het"
+)
+
+// The articles nouns can take by base language, combinations are written with a slash.
+// Languages without an entry get no article field.
+var articlesByLanguage = map[string][]string{
+	"nl": {articleDe, articleHet, articleDeHet},
+	"de": {"der", "die", "das", "der/die", "der/das", "die/das"},
+}
+
+// The order articles are written in when they are combined
+var articleOrder = []string{"de", "het", "der", "die", "das"}
+
+var responseBadNounForms = `<p>Lidwoord must be one of the articles of the list's language, meervoud and verkleinwoord can only contain letters, ' and -</p>`
+
+// Checks the noun fields of a word coming from the add form, articles are the ones of the list's language
+func validateNounForms(word Word, articles []string) error {
+	if word.Lidwoord != "" && !slices.Contains(articles, word.Lidwoord) {
+		return fmt.Errorf("unknown article %q", word.Lidwoord)
+	}
+	for _, form := range []string{word.Meervoud, word.Verkleinwoord} {
+		if !isWordForm(form) {
+			return fmt.Errorf("%q doesn't look like a word", form)
+		}
+	}
+	return nil
+}
+
+// Letters, apostrophes (auto's) and hyphens (e-mails) only. Empty is fine.
+func isWordForm(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != '\'' && r != '’' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Which saved articles a filter value matches, nil means no filtering.
+// A single article also matches the combinations it is part of, de matches de/het.
+func articlesMatching(filter string) []string {
+	filter = strings.ToLower(filter)
+	if filter == "" {
+		return nil
+	}
+	var matching []string
+	for _, language := range []string{"nl", "de"} {
+		for _, article := range articlesByLanguage[language] {
+			if article == filter || !strings.Contains(filter, "/") && slices.Contains(strings.Split(article, "/"), filter) {
+				matching = append(matching, article)
+			}
+		}
+	}
+	return matching
+}
+
+// Two different articles for the same noun mean it takes both
+func mergeArticle(saved, new string) string {
+	switch {
+	case new == "" || new == saved:
+		return saved
+	case saved == "":
+		return new
+	}
+	var merged []string
+	for _, article := range articleOrder {
+		if slices.Contains(strings.Split(saved, "/"), article) || slices.Contains(strings.Split(new, "/"), article) {
+			merged = append(merged, article)
+		}
+	}
+	return strings.Join(merged, "/")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNounForms(t *testing.T) {
+	tests := []struct {
+		name  string
+		word  Word
+		valid bool
+	}{
+		{name: "Not a noun", word: Word{Woord: "lopen"}, valid: true},
+		{name: "Het-word", word: Word{Woord: "huis", Lidwoord: "het", Meervoud: "huizen", Verkleinwoord: "huisje"}, valid: true},
+		{name: "Plural with apostrophe", word: Word{Woord: "auto", Lidwoord: "de", Meervoud: "auto's"}, valid: true},
+		{name: "Both articles", word: Word{Woord: "pad", Lidwoord: "de/het"}, valid: true},
+		{name: "Unknown article", word: Word{Woord: "huis", Lidwoord: "le"}, valid: false},
+		{name: "German article in a Dutch list", word: Word{Woord: "huis", Lidwoord: "das"}, valid: false},
+		{name: "Plural with spaces", word: Word{Woord: "huis", Meervoud: "twee huizen"}, valid: false},
+		{name: "Diminutive with markup", word: Word{Woord: "huis", Verkleinwoord: "<b>huisje</b>"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNounForms(tt.word, articlesByLanguage["nl"])
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestArticlesMatching(t *testing.T) {
+	assert.Nil(t, articlesMatching(""))
+	assert.Equal(t, []string{"het", "de/het"}, articlesMatching("het"))
+	assert.Equal(t, []string{"de", "de/het"}, articlesMatching("DE"))
+	assert.Equal(t, []string{"das", "der/das", "die/das"}, articlesMatching("das"))
+	assert.Equal(t, []string{"de/het"}, articlesMatching("de/het"))
+}
+
+func TestMergeArticle(t *testing.T) {
+	assert.Equal(t, "het", mergeArticle("het", ""))
+	assert.Equal(t, "het", mergeArticle("", "het"))
+	assert.Equal(t, "de/het", mergeArticle("de", "het"))
+	assert.Equal(t, "de/het", mergeArticle("het", "de"))
+	assert.Equal(t, "der/die", mergeArticle("die", "der"))
+}
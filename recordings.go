@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+var responseBadAudio = `<p>Only MP3, Ogg, WAV, WebM and MP4 audio can be uploaded</p>`
+
+// Recording is an audio clip of how a word is pronounced
+type Recording struct {
+	ContentType string
+	Data        []byte
+}
+
+// What http.DetectContentType makes of the audio formats browsers record and people upload, and the type they are served with.
+// WebM and MP4 are containers that are sniffed as video, MediaRecorder puts only audio in them.
+var audioTypes = map[string]string{
+	"audio/mpeg":      "audio/mpeg",
+	"application/ogg": "audio/ogg",
+	"audio/wave":      "audio/wav",
+	"video/webm":      "audio/webm",
+	"video/mp4":       "audio/mp4",
+}
+
+// The content type of an audio clip, read from its first bytes. What the client claims isn't trusted.
+func sniffAudio(data []byte) (string, bool) {
+	contentType, ok := audioTypes[http.DetectContentType(data)]
+	return contentType, ok
+}
+
+func responseAudioTooLarge(limit int64) string {
+	return fmt.Sprintf("<p>Recordings can be at most %d kB</p>", limit/1024)
+}
+
+// Sends the form for recording or uploading a pronunciation
+func (c *Context) recordingForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Woord string
+		List  int
+	}{Woord: r.PathValue("woord"), List: list.ID}
+	return c.templates.Execute(w, "recording-form.html", data)
+}
+
+// Takes a multipart upload with the clip in the audio field
+func (c *Context) uploadRecording(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	limit := c.config.MaxAudioSize
+	// Leaves room for the multipart headers and the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newHTTPError(http.StatusRequestEntityTooLarge, responseAudioTooLarge(limit), err)
+		}
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+
+	file, _, err := r.FormFile("audio")
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadAudio, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return newHTTPError(http.StatusRequestEntityTooLarge, responseAudioTooLarge(limit), nil)
+	}
+	contentType, ok := sniffAudio(data)
+	if !ok {
+		return newHTTPError(http.StatusUnsupportedMediaType, responseBadAudio, nil)
+	}
+
+	err = c.store.SaveRecording(user_id, list.ID, r.PathValue("woord"), Recording{ContentType: contentType, Data: data})
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	return err
+}
+
+func (c *Context) serveRecording(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	recording, err := c.store.Recording(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No recording</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", recording.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(recording.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Recordings are only for the user who made them
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Write(recording.Data)
+	return nil
+}
+
+func (c *Context) deleteRecording(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	return c.store.DeleteRecording(user_id, list.ID, r.PathValue("woord"))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The first bytes of the formats, enough for http.DetectContentType
+var (
+	mp3Clip  = append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), make([]byte, 100)...)
+	oggClip  = append([]byte("OggS\x00\x02"), make([]byte, 100)...)
+	webmClip = append([]byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\xf7\x81\x01\x42\xf2\x81\x04\x42\xf3\x81\x08\x42\x82\x84webm"), make([]byte, 100)...)
+)
+
+func TestSniffAudio(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		ok          bool
+	}{
+		{name: "MP3", data: mp3Clip, contentType: "audio/mpeg", ok: true},
+		{name: "Ogg from Firefox", data: oggClip, contentType: "audio/ogg", ok: true},
+		{name: "WebM from Chrome", data: webmClip, contentType: "audio/webm", ok: true},
+		{name: "HTML pretending to be audio", data: []byte("<html><script>alert(1)</script>"), ok: false},
+		{name: "Empty", data: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, ok := sniffAudio(tt.data)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.contentType, contentType)
+		})
+	}
+}
+
+func TestRecordingHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	c.config.MaxAudioSize = 1024
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	db.Exec("INSERT INTO words (user_id, list_id, word) VALUES (1, 1, 'fiets')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("GET /recording/{woord}", appHandler(c.serveRecording))
+	router.Handle("POST /recording/{woord}", appHandler(c.uploadRecording))
+	router.Handle("DELETE /recording/{woord}", appHandler(c.deleteRecording))
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	upload := func(word string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("audio", "opname.mp3")
+		part.Write(data)
+		form.Close()
+		req, _ := http.NewRequest("POST", "/recording/"+word, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return do(req)
+	}
+	get := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return do(req)
+	}
+
+	assert.Equal(t, http.StatusNotFound, get("GET", "/recording/fiets").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload("fiets", []byte("<html><script>alert(1)</script>")).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload("fiets", append(mp3Clip, make([]byte, 2048)...)).Code)
+	assert.Equal(t, http.StatusNotFound, upload("lopen", mp3Clip).Code)
+	require.Equal(t, http.StatusOK, upload("fiets", mp3Clip).Code)
+
+	rr := get("GET", "/recording/fiets")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "audio/mpeg", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, mp3Clip, rr.Body.Bytes())
+	assert.Contains(t, get("POST", "/").Body.String(), `<audio controls preload="none" src="/recording/fiets?list=1">`)
+
+	require.Equal(t, http.StatusOK, upload("fiets", oggClip).Code, "a new recording replaces the old one")
+	assert.Equal(t, "audio/ogg", get("GET", "/recording/fiets").Header().Get("Content-Type"))
+
+	require.Equal(t, http.StatusOK, get("DELETE", "/recording/fiets").Code)
+	assert.Equal(t, http.StatusNotFound, get("GET", "/recording/fiets").Code)
+	assert.NotContains(t, get("POST", "/").Body.String(), "<audio")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var errNoSynthesizer = errors.New("no speech synthesizer available")
+
+// How long generating the pronunciation of one word may take
+const synthesizeTimeout = 10 * time.Second
+
+// Synthesizer turns text into spoken audio, for words that have no recording
+type Synthesizer interface {
+	// Speaks text in the language of the BCP-47 tag. Returns errNoSynthesizer when there is no engine to do it.
+	Synthesize(ctx context.Context, text, language string) (Recording, error)
+}
+
+// Picks espeak-ng when it is installed, with a cache in front of it when there is a cache directory
+func newSynthesizer(cfg Config) Synthesizer {
+	if cfg.EspeakPath == "" {
+		return noSynthesizer{}
+	}
+	path, err := exec.LookPath(cfg.EspeakPath)
+	if err != nil {
+		log.Printf("%s not found, generated pronunciation is turned off", cfg.EspeakPath)
+		return noSynthesizer{}
+	}
+	var synthesizer Synthesizer = espeakSynthesizer{path: path}
+	if cfg.SpeechCacheDir != "" {
+		synthesizer = cachedSynthesizer{next: synthesizer, dir: cfg.SpeechCacheDir}
+	}
+	return synthesizer
+}
+
+// Whether the table should offer generated pronunciation
+func canSynthesize(s Synthesizer) bool {
+	_, none := s.(noSynthesizer)
+	return s != nil && !none
+}
+
+// noSynthesizer is used when no engine is installed
+type noSynthesizer struct{}
+
+func (noSynthesizer) Synthesize(ctx context.Context, text, language string) (Recording, error) {
+	return Recording{}, errNoSynthesizer
+}
+
+// espeakSynthesizer runs the espeak-ng command line program, which writes WAV to stdout
+type espeakSynthesizer struct {
+	path string
+}
+
+func (e espeakSynthesizer) Synthesize(ctx context.Context, text, language string) (Recording, error) {
+	// espeak-ng names its voices after languages, it has no voices for most regions
+	cmd := exec.CommandContext(ctx, e.path, "-v", baseLanguage(language), "--stdout", "--", text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return Recording{}, fmt.Errorf("running espeak-ng: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return Recording{}, errors.New("espeak-ng wrote no audio")
+	}
+	return Recording{ContentType: "audio/wav", Data: stdout.Bytes()}, nil
+}
+
+// cachedSynthesizer keeps what next made in dir, one file per text and language, so every word is only synthesized once
+type cachedSynthesizer struct {
+	next Synthesizer
+	dir  string
+}
+
+// File extensions of the audio types synthesizers produce
+var speechExtensions = map[string]string{
+	"audio/wav": ".wav",
+	"audio/ogg": ".ogg",
+}
+
+func (c cachedSynthesizer) Synthesize(ctx context.Context, text, language string) (Recording, error) {
+	sum := sha256.Sum256([]byte(language + "\n" + text))
+	base := filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+	for contentType, ext := range speechExtensions {
+		if data, err := os.ReadFile(base + ext); err == nil {
+			return Recording{ContentType: contentType, Data: data}, nil
+		}
+	}
+
+	recording, err := c.next.Synthesize(ctx, text, language)
+	if err != nil {
+		return Recording{}, err
+	}
+	ext, ok := speechExtensions[recording.ContentType]
+	if !ok {
+		return recording, nil
+	}
+	// A failing cache only costs time, the audio is sent anyway
+	if err := writeFileAtomic(base+ext, recording.Data); err != nil {
+		log.Printf("Failed to cache speech: %v", err)
+	}
+	return recording, nil
+}
+
+// Writes to a temporary file first, so a reader never sees half a file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Serves generated pronunciation of a saved word, in the language of its list
+func (c *Context) serveSpeech(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	word, err := c.store.Word(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), synthesizeTimeout)
+	defer cancel()
+	speech, err := c.synthesizer.Synthesize(ctx, word.Woord, list.SourceLanguage)
+	if err == errNoSynthesizer {
+		return newHTTPError(http.StatusNotFound, "<p>Generated pronunciation is turned off</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", speech.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(speech.Data)
+	return nil
+}
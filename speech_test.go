@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes a shell script that stands in for espeak-ng: it saves its arguments next to itself and prints a WAV header
+func fakeEspeak(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "espeak-ng")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+// countingSynthesizer says every text as "audio" and counts how often it was asked
+type countingSynthesizer struct {
+	calls int
+}
+
+func (s *countingSynthesizer) Synthesize(ctx context.Context, text, language string) (Recording, error) {
+	s.calls++
+	return Recording{ContentType: "audio/wav", Data: []byte("RIFF " + language + " " + text)}, nil
+}
+
+func TestEspeakSynthesizer(t *testing.T) {
+	path := fakeEspeak(t, `echo "$@" > "$(dirname "$0")/args"; printf 'RIFF\0\0\0\0WAVE'`)
+	speech, err := espeakSynthesizer{path: path}.Synthesize(context.Background(), "fiets", "nl-BE")
+	require.NoError(t, err)
+	assert.Equal(t, Recording{ContentType: "audio/wav", Data: []byte("RIFF\x00\x00\x00\x00WAVE")}, speech)
+	args, _ := os.ReadFile(filepath.Join(filepath.Dir(path), "args"))
+	assert.Equal(t, "-v nl --stdout -- fiets\n", string(args))
+
+	failing := fakeEspeak(t, `echo "unknown voice" >&2; exit 1`)
+	_, err = espeakSynthesizer{path: failing}.Synthesize(context.Background(), "fiets", "nl")
+	assert.ErrorContains(t, err, "unknown voice")
+}
+
+func TestCachedSynthesizer(t *testing.T) {
+	next := &countingSynthesizer{}
+	cached := cachedSynthesizer{next: next, dir: filepath.Join(t.TempDir(), "cache")}
+
+	first, err := cached.Synthesize(context.Background(), "fiets", "nl")
+	require.NoError(t, err)
+	again, err := cached.Synthesize(context.Background(), "fiets", "nl")
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+	assert.Equal(t, 1, next.calls, "the second time comes from the cache")
+
+	_, err = cached.Synthesize(context.Background(), "fiets", "de")
+	require.NoError(t, err)
+	assert.Equal(t, 2, next.calls, "cached per language")
+}
+
+func TestNewSynthesizer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.EspeakPath = ""
+	assert.False(t, canSynthesize(newSynthesizer(cfg)), "turned off")
+
+	cfg.EspeakPath = "espeak-ng-that-is-not-installed"
+	assert.False(t, canSynthesize(newSynthesizer(cfg)), "not installed")
+
+	cfg.EspeakPath = fakeEspeak(t, "")
+	cfg.SpeechCacheDir = t.TempDir()
+	assert.IsType(t, cachedSynthesizer{}, newSynthesizer(cfg))
+}
+
+func TestSpeechHandler(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name, source_language) VALUES (1, 'Duits', 'de')")
+	db.Exec("INSERT INTO words (user_id, list_id, word) VALUES (1, 1, 'Haus')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("GET /speech/{woord}", appHandler(c.serveSpeech))
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("GET", "/speech/Haus").Code, "no engine")
+	assert.NotContains(t, do("POST", "/").Body.String(), "/speech/")
+
+	c.synthesizer = &countingSynthesizer{}
+	rr := do("GET", "/speech/Haus")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "audio/wav", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "RIFF de Haus", rr.Body.String(), "spoken in the language of the list")
+	assert.Contains(t, do("POST", "/").Body.String(), `src="/speech/Haus?list=1"`)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/speech/Maus").Code)
+}
@@ -0,0 +1,1464 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// errNotFound is returned by stores when the requested row doesn't exist
+var errNotFound = errors.New("not found")
+
+type User struct {
+	ID             int
+	Username       string
+	HashedPassword string
+}
+
+// ConflictMode tells AddWord what to do when the user already has the word
+type ConflictMode string
+
+const (
+	conflictAsk       ConflictMode = ""          // don't change anything, return a *WordExistsError
+	conflictMerge     ConflictMode = "merge"     // fold the new sense into the first one, see mergeWords
+	conflictOverwrite ConflictMode = "overwrite" // replace the saved word and all its senses
+	conflictKeepBoth  ConflictMode = "keep-both" // add the new senses after the saved ones
+)
+
+// WordExistsError is returned by AddWord in conflictAsk mode, Existing is what is already saved
+type WordExistsError struct {
+	Existing Word
+}
+
+func (e *WordExistsError) Error() string {
+	return fmt.Sprintf("word %q already exists", e.Existing.Woord)
+}
+
+// WordFilter narrows down SearchWords, zero values don't filter anything
+type WordFilter struct {
+	List    int      // only words in this list, 0 means all of the user's lists
+	Search  string   // case-insensitive substring of the word, its noun forms or any of its senses
+	Article string   // de, het or de/het, see articlesMatching
+	Tags    []string // words need every one of these tags
+
+	HasExamples bool // only words with at least one example sentence
+}
+
+// Words are looked up by their text within one of the user's lists, a list of another user is never found
+type WordStore interface {
+	// Words of the user that pass the filter, matching words come with all of their senses
+	SearchWords(userID int, filter WordFilter) ([]Word, error)
+	// A single saved word with everything attached to it, or errNotFound
+	Word(userID, listID int, word string) (Word, error)
+	// Words in the list, or in all of the user's lists when listID is 0
+	CountWords(userID, listID int) (int, error)
+	// Returns errNotFound when the list doesn't exist
+	AddWord(userID, listID int, word Word, onConflict ConflictMode) error
+	// Deletes the sense at position, or the whole word when position is 0.
+	// A word that loses its last sense is deleted too.
+	DeleteWord(userID, listID int, word string, position int) error
+}
+
+type ConjugationStore interface {
+	// Saves the conjugation of a saved word, an empty conjugation removes it. Returns errNotFound for unknown words.
+	SaveConjugation(userID, listID int, word string, conjugation Conjugation) error
+}
+
+type TagStore interface {
+	// Every tag the user has on at least one word, sorted by name
+	Tags(userID int) ([]string, error)
+	// Replaces the tags of a saved word. Returns errNotFound for unknown words.
+	SetTags(userID, listID int, word string, tags []string) error
+}
+
+type ExampleStore interface {
+	// Adds an example sentence to a saved word and returns it with its id. Returns errNotFound for unknown words.
+	AddExample(userID, listID int, word string, example Example) (Example, error)
+	// Returns errNotFound when the word has no such example
+	DeleteExample(userID, listID int, word string, exampleID int) error
+}
+
+type RecordingStore interface {
+	// Saves the pronunciation recording of a word, replacing the one it had. Returns errNotFound for unknown words.
+	SaveRecording(userID, listID int, word string, recording Recording) error
+	// Returns errNotFound when the word has no recording
+	Recording(userID, listID int, word string) (Recording, error)
+	DeleteRecording(userID, listID int, word string) error
+}
+
+type ImageStore interface {
+	// Saves the picture of a word, replacing the one it had. Returns errNotFound for unknown words
+	// and errQuotaExceeded when the user's pictures would take more than quota bytes.
+	SaveImage(userID, listID int, word string, img Image, quota int64) error
+	// Returns errNotFound when the word has no picture
+	Image(userID, listID int, word string) (Image, error)
+	DeleteImage(userID, listID int, word string) error
+}
+
+type ReferenceStore interface {
+	// Replaces the reference words of the language with glosses in glossLanguage by what next returns until io.EOF.
+	// Nothing changes when next fails. Returns how many words were saved.
+	ImportReference(language, glossLanguage string, next func() (ReferenceEntry, error)) (int, error)
+	// Entries for the word in the language ignoring case, in the order they were imported
+	ReferenceEntries(language, word string) ([]ReferenceEntry, error)
+	// Entries for each of the words like ReferenceEntries, words that aren't in the reference are left out
+	ReferenceEntriesOf(language string, words []string) (map[string][]ReferenceEntry, error)
+}
+
+type FrequencyStore interface {
+	// Replaces the frequency list of the language, words are ranked in the order they are given.
+	// Spellings that only differ in case must be merged before.
+	ImportFrequencies(language string, words []WordFrequency) error
+	// Ranks of the words in the frequency list of the language ignoring case, words that aren't in it are left out
+	FrequencyRanks(language string, words []string) (map[string]int, error)
+	// At most limit words of the frequency list ranked below after, most frequent first
+	FrequentWords(language string, after, limit int) ([]WordFrequency, error)
+}
+
+type ListStore interface {
+	// The user's lists in the order they were made, with their word counts
+	Lists(userID int) ([]List, error)
+	// Returns errNotFound when the user has no such list
+	List(userID, listID int) (List, error)
+	// The user's first list, made on the spot for users who have none yet
+	DefaultList(userID int) (List, error)
+	// Returns errListExists when the user already has a list with that name
+	CreateList(userID int, list List) (List, error)
+	// Renames the list and changes its languages. Returns errNotFound for lists that aren't the user's
+	// and errListExists when another list has the name.
+	UpdateList(userID int, list List) error
+	// Moves a word with everything attached to it to another list, or copies it when keepOriginal is set.
+	// Returns a *WordExistsError when the other list already has the word and errQuotaExceeded when a copied
+	// picture would make the user's pictures take more than imageQuota bytes.
+	TransferWord(userID int, word string, from, to int, keepOriginal bool, imageQuota int64) error
+}
+
+type UserStore interface {
+	// Returns errNotFound when there is no such user
+	UserByName(username string) (User, error)
+	// Creates the user and their first session in one transaction, returns the new user's id
+	CreateUserWithSession(username, hashedPassword, sessionKey string) (int, error)
+}
+
+type SessionStore interface {
+	CreateSession(userID int, sessionKey string) error
+	// Returns errNotFound when the session key is unknown
+	SessionUser(sessionKey string) (User, error)
+	DeleteSession(sessionKey string) error
+}
+
+type Store interface {
+	WordStore
+	ConjugationStore
+	TagStore
+	ExampleStore
+	RecordingStore
+	ImageStore
+	ReferenceStore
+	FrequencyStore
+	ListStore
+	UserStore
+	SessionStore
+	Close() error
+}
+
+// dialect holds what differs between the databases we support.
+// Queries are written with ? placeholders and rebound for databases that want something else.
+type dialect struct {
+	name           string
+	driver         string
+	migrationsDir  string
+	numberedParams bool   // $1, $2... instead of ?
+	lower          string // function that lowercases every letter, not only ASCII ones
+
+	// Whether err comes from a UNIQUE or PRIMARY KEY constraint, checks before an insert miss concurrent requests
+	isUniqueViolation func(err error) bool
+
+	// Migrations run with foreign keys turned off, so dropping or rebuilding a table doesn't cascade to the rows pointing at it
+	pauseForeignKeys bool
+}
+
+// A case-insensitive LIKE on column, for a pattern that is lowercased already
+func (d dialect) like(column string) string {
+	return d.lower + "(" + column + ") LIKE ?"
+}
+
+func (d dialect) rebind(query string) string {
+	if !d.numberedParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sqlStore implements Store on top of database/sql, the dialect takes care of the differences between databases
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// Opens the database chosen in the config. The schema isn't touched, see migrateToLatest.
+func openStore(cfg Config) (*sqlStore, error) {
+	switch cfg.DatabaseDriver {
+	case "sqlite":
+		return openSQLiteStore(cfg.DatabasePath)
+	case "postgres":
+		return openPostgresStore(cfg.PostgresURL)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.DatabaseDriver)
+	}
+}
+
+func (s *sqlStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) queryRow(query string, args ...any) *sql.Row {
+	return s.db.QueryRow(s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStore) SearchWords(userID int, filter WordFilter) ([]Word, error) {
+	searchString := "%" + strings.ToLower(filter.Search) + "%"
+	like := s.dialect.like
+	q := `
+	SELECT
+		w.id, w.list_id, w.word, w.pronunciation, w.article, w.plural, w.diminutive,
+		EXISTS (SELECT 1 FROM recordings r WHERE r.word_id = w.id),
+		EXISTS (SELECT 1 FROM images i WHERE i.word_id = w.id),
+		s.position, s.part_of_speech, s.translation, s.notes,
+		` + conjugationColumns("c") + `
+	FROM
+		words w
+	LEFT JOIN
+		senses s
+	ON
+		s.word_id = w.id
+	LEFT JOIN
+		conjugations c
+	ON
+		c.word_id = w.id
+	WHERE
+		w.user_id = ? AND (
+			` + like("w.word") + ` OR ` + like("w.plural") + ` OR ` + like("w.diminutive") + `
+			OR EXISTS (SELECT 1 FROM senses m WHERE m.word_id = w.id AND (` + like("m.translation") + ` OR ` + like("m.notes") + `))
+			OR EXISTS (SELECT 1 FROM conjugations f WHERE f.word_id = w.id AND (` + conjugationFormsLike("f", like) + `))
+		)`
+	args := []any{userID, searchString, searchString, searchString, searchString, searchString}
+	for range (Conjugation{}).forms() {
+		args = append(args, searchString)
+	}
+
+	if filter.List != 0 {
+		q += ` AND w.list_id = ?`
+		args = append(args, filter.List)
+	}
+	if articles := articlesMatching(filter.Article); articles != nil {
+		q += ` AND w.article IN (?` + strings.Repeat(", ?", len(articles)-1) + `)`
+		for _, article := range articles {
+			args = append(args, article)
+		}
+	}
+	if filter.HasExamples {
+		q += ` AND EXISTS (SELECT 1 FROM examples e WHERE e.word_id = w.id)`
+	}
+	for _, tag := range filter.Tags {
+		q += ` AND w.id IN (SELECT wt.word_id FROM word_tags wt JOIN tags t ON t.id = wt.tag_id WHERE t.user_id = ? AND t.name = ?)`
+		args = append(args, userID, tag)
+	}
+	q += `
+	ORDER BY w.id, s.position;`
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching words: %w", err)
+	}
+	defer rows.Close()
+
+	var words []Word
+	var ids []int
+	lastID := 0
+	for rows.Next() {
+		var id int
+		var word Word
+		var pronunciation sql.NullString
+		var position sql.NullInt64
+		var partOfSpeech, translation, notes sql.NullString
+		var conjugation nullConjugation
+		dest := []any{&id, &word.List, &word.Woord, &pronunciation, &word.Lidwoord, &word.Meervoud, &word.Verkleinwoord, &word.Opname, &word.Afbeelding,
+			&position, &partOfSpeech, &translation, &notes}
+		if err := rows.Scan(append(dest, conjugation.dest()...)...); err != nil {
+			return nil, fmt.Errorf("reading word row: %w", err)
+		}
+		word.Uitspraak = pronunciation.String
+		word.Vervoeging = conjugation.get()
+
+		// Rows come ordered by word, one per sense
+		if id != lastID {
+			words = append(words, word)
+			ids = append(ids, id)
+			lastID = id
+		}
+		if position.Valid {
+			current := &words[len(words)-1]
+			current.Senses = append(current.Senses, Sense{
+				Position:   int(position.Int64),
+				Woordsoort: partOfSpeech.String,
+				Vertaling:  translation.String,
+				Notes:      notes.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating word rows: %w", err)
+	}
+
+	tags, err := s.wordTags(s.db, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := s.wordExamples(s.db, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		words[i].Tags = tags[id]
+		words[i].Voorbeelden = examples[id]
+	}
+	return words, nil
+}
+
+// Tag names by word id, sorted, for the words with the given ids
+func (s *sqlStore) wordTags(tx querier, userID int, wordIDs []int) (map[int][]string, error) {
+	tags := map[int][]string{}
+	for start := 0; start < len(wordIDs); start += queryBatch {
+		batch := wordIDs[start:min(start+queryBatch, len(wordIDs))]
+		args := []any{userID}
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		rows, err := tx.Query(s.dialect.rebind(`SELECT wt.word_id, t.name FROM word_tags wt JOIN tags t ON t.id = wt.tag_id
+			WHERE t.user_id = ? AND wt.word_id IN (?`+strings.Repeat(", ?", len(batch)-1)+`) ORDER BY t.name`), args...)
+		if err != nil {
+			return nil, fmt.Errorf("looking up tags: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading tag row: %w", err)
+			}
+			tags[id] = append(tags[id], name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func (s *sqlStore) CountWords(userID, listID int) (int, error) {
+	q := "SELECT COUNT(*) FROM words WHERE user_id = ?"
+	args := []any{userID}
+	if listID != 0 {
+		q += " AND list_id = ?"
+		args = append(args, listID)
+	}
+	var count int
+	err := s.queryRow(q, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting words: %w", err)
+	}
+	return count, nil
+}
+
+func (s *sqlStore) AddWord(userID, listID int, word Word, onConflict ConflictMode) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.loadList(tx, userID, listID); err != nil {
+		return err
+	}
+	wordID, saved, err := s.loadWord(tx, userID, listID, word.Woord)
+	if err != nil && err != errNotFound {
+		return err
+	}
+
+	switch {
+	case err == errNotFound:
+		_, err = s.insertWord(tx, userID, listID, word)
+
+	case onConflict == conflictAsk:
+		return &WordExistsError{Existing: saved}
+
+	case onConflict == conflictOverwrite:
+		err = s.replaceWord(tx, wordID, word)
+		if err == nil {
+			err = s.setTags(tx, userID, wordID, word.Tags)
+		}
+
+	case onConflict == conflictMerge:
+		err = s.replaceWord(tx, wordID, mergeWords(saved, word))
+		if err == nil {
+			err = s.addTags(tx, userID, wordID, word.Tags)
+		}
+
+	case onConflict == conflictKeepBoth:
+		next := 1
+		if len(saved.Senses) > 0 {
+			next = saved.Senses[len(saved.Senses)-1].Position + 1
+		}
+		err = s.insertSenses(tx, wordID, next, word.Senses)
+		if err == nil {
+			err = s.addTags(tx, userID, wordID, word.Tags)
+		}
+
+	default:
+		return fmt.Errorf("unknown conflict mode %q", onConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("saving word %q: %w", word.Woord, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Word(userID, listID int, word string) (Word, error) {
+	_, saved, err := s.loadWord(s.db, userID, listID, word)
+	return saved, err
+}
+
+// Stores a word that isn't in the list yet with its senses, tags, examples and conjugation, and returns its id
+func (s *sqlStore) insertWord(tx *sql.Tx, userID, listID int, word Word) (int, error) {
+	var wordID int
+	err := tx.QueryRow(s.dialect.rebind("INSERT INTO words (user_id, list_id, word, pronunciation, article, plural, diminutive) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id"),
+		userID, listID, word.Woord, word.Uitspraak, word.Lidwoord, word.Meervoud, word.Verkleinwoord).Scan(&wordID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.insertSenses(tx, wordID, 1, word.Senses); err != nil {
+		return 0, err
+	}
+	if err := s.addTags(tx, userID, wordID, word.Tags); err != nil {
+		return 0, err
+	}
+	for _, example := range word.Voorbeelden {
+		if _, err := s.insertExample(tx, wordID, example); err != nil {
+			return 0, err
+		}
+	}
+	if word.Vervoeging != nil {
+		if err := s.saveConjugation(tx, wordID, *word.Vervoeging); err != nil {
+			return 0, err
+		}
+	}
+	return wordID, nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// The saved word with its senses, conjugation and its id, or errNotFound
+func (s *sqlStore) loadWord(tx querier, userID, listID int, word string) (int, Word, error) {
+	var id int
+	var pronunciation sql.NullString
+	var conjugation nullConjugation
+	saved := Word{Woord: word, List: listID}
+	q := `
+	SELECT
+		w.id, w.pronunciation, w.article, w.plural, w.diminutive,
+		EXISTS (SELECT 1 FROM recordings r WHERE r.word_id = w.id),
+		EXISTS (SELECT 1 FROM images i WHERE i.word_id = w.id),
+		` + conjugationColumns("c") + `
+	FROM
+		words w
+	LEFT JOIN
+		conjugations c
+	ON
+		c.word_id = w.id
+	WHERE
+		w.user_id = ? AND w.list_id = ? AND w.word = ?`
+	dest := []any{&id, &pronunciation, &saved.Lidwoord, &saved.Meervoud, &saved.Verkleinwoord, &saved.Opname, &saved.Afbeelding}
+	err := tx.QueryRow(s.dialect.rebind(q), userID, listID, word).Scan(append(dest, conjugation.dest()...)...)
+	if err == sql.ErrNoRows {
+		return 0, Word{}, errNotFound
+	}
+	if err != nil {
+		return 0, Word{}, fmt.Errorf("looking up word %q: %w", word, err)
+	}
+	saved.Uitspraak = pronunciation.String
+	saved.Vervoeging = conjugation.get()
+
+	rows, err := tx.Query(s.dialect.rebind("SELECT position, part_of_speech, translation, notes FROM senses WHERE word_id = ? ORDER BY position"), id)
+	if err != nil {
+		return 0, Word{}, fmt.Errorf("looking up senses of %q: %w", word, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sense Sense
+		if err := rows.Scan(&sense.Position, &sense.Woordsoort, &sense.Vertaling, &sense.Notes); err != nil {
+			return 0, Word{}, fmt.Errorf("reading sense row: %w", err)
+		}
+		saved.Senses = append(saved.Senses, sense)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, Word{}, err
+	}
+
+	tags, err := s.wordTags(tx, userID, []int{id})
+	if err != nil {
+		return 0, Word{}, err
+	}
+	saved.Tags = tags[id]
+
+	examples, err := s.wordExamples(tx, userID, []int{id})
+	if err != nil {
+		return 0, Word{}, err
+	}
+	saved.Voorbeelden = examples[id]
+	return id, saved, nil
+}
+
+// Stores senses at positions starting from first, in the order they are given
+func (s *sqlStore) insertSenses(tx *sql.Tx, wordID, first int, senses []Sense) error {
+	insert := s.dialect.rebind("INSERT INTO senses (word_id, position, part_of_speech, translation, notes) VALUES (?, ?, ?, ?, ?)")
+	for i, sense := range senses {
+		if _, err := tx.Exec(insert, wordID, first+i, sense.Woordsoort, sense.Vertaling, sense.Notes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the pronunciation, noun forms and all senses of a saved word, senses are numbered from 1 again
+func (s *sqlStore) replaceWord(tx *sql.Tx, wordID int, word Word) error {
+	_, err := tx.Exec(s.dialect.rebind("UPDATE words SET pronunciation = ?, article = ?, plural = ?, diminutive = ? WHERE id = ?"),
+		word.Uitspraak, word.Lidwoord, word.Meervoud, word.Verkleinwoord, wordID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.dialect.rebind("DELETE FROM senses WHERE word_id = ?"), wordID); err != nil {
+		return err
+	}
+	return s.insertSenses(tx, wordID, 1, word.Senses)
+}
+
+func (s *sqlStore) DeleteWord(userID, listID int, word string, position int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, saved, err := s.loadWord(tx, userID, listID, word)
+	if err == errNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Senses, conjugations, examples, recordings and images go along through ON DELETE CASCADE.
+	// Tags are let go of first, so the ones no other word has are deleted.
+	if position == 0 || len(saved.Senses) <= 1 {
+		err = s.setTags(tx, userID, wordID, nil)
+		if err == nil {
+			_, err = tx.Exec(s.dialect.rebind("DELETE FROM words WHERE id = ?"), wordID)
+		}
+	} else {
+		_, err = tx.Exec(s.dialect.rebind("DELETE FROM senses WHERE word_id = ? AND position = ?"), wordID, position)
+	}
+	if err != nil {
+		return fmt.Errorf("deleting word %q: %w", word, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) SaveConjugation(userID, listID int, word string, conjugation Conjugation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return err
+	}
+	if err := s.saveConjugation(tx, wordID, conjugation); err != nil {
+		return fmt.Errorf("saving conjugation of %q: %w", word, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) saveConjugation(tx *sql.Tx, wordID int, conjugation Conjugation) error {
+	var err error
+	if conjugation.isEmpty() {
+		_, err = tx.Exec(s.dialect.rebind("DELETE FROM conjugations WHERE word_id = ?"), wordID)
+	} else {
+		_, err = tx.Exec(s.dialect.rebind(`
+		INSERT INTO conjugations (word_id, `+conjugationColumns("")+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (word_id) DO UPDATE SET
+			present_ik = excluded.present_ik,
+			present_jij = excluded.present_jij,
+			present_hij = excluded.present_hij,
+			present_plural = excluded.present_plural,
+			past_singular = excluded.past_singular,
+			past_plural = excluded.past_plural,
+			past_participle = excluded.past_participle,
+			auxiliary = excluded.auxiliary`),
+			wordID, conjugation.Ik, conjugation.Jij, conjugation.Hij, conjugation.Meervoud,
+			conjugation.VerledenEnkelvoud, conjugation.VerledenMeervoud, conjugation.VoltooidDeelwoord, conjugation.Hulpwerkwoord)
+	}
+	return err
+}
+
+func (s *sqlStore) Tags(userID int) ([]string, error) {
+	rows, err := s.query("SELECT name FROM tags WHERE user_id = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("reading tag row: %w", err)
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+func (s *sqlStore) SetTags(userID, listID int, word string, tags []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return err
+	}
+	if err := s.setTags(tx, userID, wordID, tags); err != nil {
+		return fmt.Errorf("saving tags of %q: %w", word, err)
+	}
+	return tx.Commit()
+}
+
+// Adds tags to a word, creating the ones the user doesn't have yet
+func (s *sqlStore) addTags(tx *sql.Tx, userID, wordID int, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.Exec(s.dialect.rebind("INSERT INTO tags (user_id, name) VALUES (?, ?) ON CONFLICT (user_id, name) DO NOTHING"), userID, tag)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.dialect.rebind(`
+		INSERT INTO word_tags (word_id, tag_id)
+		SELECT ?, id FROM tags WHERE user_id = ? AND name = ?
+		ON CONFLICT (word_id, tag_id) DO NOTHING`), wordID, userID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the tags of a word and drops tags that no word uses anymore
+func (s *sqlStore) setTags(tx *sql.Tx, userID, wordID int, tags []string) error {
+	if _, err := tx.Exec(s.dialect.rebind("DELETE FROM word_tags WHERE word_id = ?"), wordID); err != nil {
+		return err
+	}
+	if err := s.addTags(tx, userID, wordID, tags); err != nil {
+		return err
+	}
+	_, err := tx.Exec(s.dialect.rebind("DELETE FROM tags WHERE user_id = ? AND id NOT IN (SELECT tag_id FROM word_tags)"), userID)
+	return err
+}
+
+func (s *sqlStore) AddExample(userID, listID int, word string, example Example) (Example, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Example{}, fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return Example{}, err
+	}
+	example.ID, err = s.insertExample(tx, wordID, example)
+	if err != nil {
+		return Example{}, fmt.Errorf("saving example of %q: %w", word, err)
+	}
+	return example, tx.Commit()
+}
+
+func (s *sqlStore) insertExample(tx *sql.Tx, wordID int, example Example) (int, error) {
+	var id int
+	err := tx.QueryRow(s.dialect.rebind("INSERT INTO examples (word_id, sentence, translation) VALUES (?, ?, ?) RETURNING id"),
+		wordID, example.Zin, example.Vertaling).Scan(&id)
+	return id, err
+}
+
+func (s *sqlStore) DeleteExample(userID, listID int, word string, exampleID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(s.dialect.rebind("DELETE FROM examples WHERE id = ? AND word_id = ?"), exampleID, wordID)
+	if err != nil {
+		return fmt.Errorf("deleting example %d: %w", exampleID, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errNotFound
+	}
+	return tx.Commit()
+}
+
+// Examples by word id in the order they were added, for the words with the given ids
+func (s *sqlStore) wordExamples(tx querier, userID int, wordIDs []int) (map[int][]Example, error) {
+	examples := map[int][]Example{}
+	for start := 0; start < len(wordIDs); start += queryBatch {
+		batch := wordIDs[start:min(start+queryBatch, len(wordIDs))]
+		args := []any{userID}
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		rows, err := tx.Query(s.dialect.rebind(`SELECT e.word_id, e.id, e.sentence, e.translation FROM examples e JOIN words w ON w.id = e.word_id
+			WHERE w.user_id = ? AND e.word_id IN (?`+strings.Repeat(", ?", len(batch)-1)+`) ORDER BY e.id`), args...)
+		if err != nil {
+			return nil, fmt.Errorf("looking up examples: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			var example Example
+			if err := rows.Scan(&id, &example.ID, &example.Zin, &example.Vertaling); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading example row: %w", err)
+			}
+			examples[id] = append(examples[id], example)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return examples, nil
+}
+
+func (s *sqlStore) SaveRecording(userID, listID int, word string, recording Recording) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(`
+	INSERT INTO recordings (word_id, content_type, data) VALUES (?, ?, ?)
+	ON CONFLICT (word_id) DO UPDATE SET content_type = excluded.content_type, data = excluded.data`),
+		wordID, recording.ContentType, recording.Data)
+	if err != nil {
+		return fmt.Errorf("saving recording of %q: %w", word, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Recording(userID, listID int, word string) (Recording, error) {
+	var recording Recording
+	err := s.queryRow(`
+	SELECT
+		r.content_type, r.data
+	FROM
+		recordings r
+	JOIN
+		words w
+	ON
+		w.id = r.word_id
+	WHERE
+		w.user_id = ? AND w.list_id = ? AND w.word = ?`, userID, listID, word).Scan(&recording.ContentType, &recording.Data)
+	if err == sql.ErrNoRows {
+		return Recording{}, errNotFound
+	}
+	if err != nil {
+		return Recording{}, fmt.Errorf("looking up recording of %q: %w", word, err)
+	}
+	return recording, nil
+}
+
+func (s *sqlStore) DeleteRecording(userID, listID int, word string) error {
+	_, err := s.exec("DELETE FROM recordings WHERE word_id IN (SELECT id FROM words WHERE user_id = ? AND list_id = ? AND word = ?)", userID, listID, word)
+	if err != nil {
+		return fmt.Errorf("deleting recording of %q: %w", word, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) SaveImage(userID, listID int, word string, img Image, quota int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.lockUser(tx, userID); err != nil {
+		return err
+	}
+	wordID, _, err := s.loadWord(tx, userID, listID, word)
+	if err != nil {
+		return err
+	}
+	// The picture being replaced doesn't count
+	used, err := s.imagesSize(tx, userID, wordID)
+	if err != nil {
+		return err
+	}
+	if used+img.size() > quota {
+		return errQuotaExceeded
+	}
+
+	_, err = tx.Exec(s.dialect.rebind(`
+	INSERT INTO images (word_id, data, thumbnail) VALUES (?, ?, ?)
+	ON CONFLICT (word_id) DO UPDATE SET data = excluded.data, thumbnail = excluded.thumbnail`),
+		wordID, img.Data, img.Thumbnail)
+	if err != nil {
+		return fmt.Errorf("saving picture of %q: %w", word, err)
+	}
+	return tx.Commit()
+}
+
+// Makes transactions that add up the user's pictures wait for each other, so two uploads can't both fit in what is left.
+// The user's row is written to keep it locked until tx ends. This has to come first: SQLite then takes its write lock
+// before anything is read, and PostgreSQL reads what the other transaction committed once it has the lock.
+func (s *sqlStore) lockUser(tx querier, userID int) error {
+	if _, err := tx.Exec(s.dialect.rebind("UPDATE users SET hashed_password = hashed_password WHERE id = ?"), userID); err != nil {
+		return fmt.Errorf("locking user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// Bytes the pictures of the user take, leaving out the picture of the word with id exceptWordID
+func (s *sqlStore) imagesSize(tx querier, userID, exceptWordID int) (int64, error) {
+	var used int64
+	err := tx.QueryRow(s.dialect.rebind(`
+	SELECT
+		COALESCE(SUM(LENGTH(i.data) + LENGTH(i.thumbnail)), 0)
+	FROM
+		images i
+	JOIN
+		words w
+	ON
+		w.id = i.word_id
+	WHERE
+		w.user_id = ? AND i.word_id <> ?`), userID, exceptWordID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("adding up pictures: %w", err)
+	}
+	return used, nil
+}
+
+func (s *sqlStore) Image(userID, listID int, word string) (Image, error) {
+	var img Image
+	err := s.queryRow(`
+	SELECT
+		i.data, i.thumbnail
+	FROM
+		images i
+	JOIN
+		words w
+	ON
+		w.id = i.word_id
+	WHERE
+		w.user_id = ? AND w.list_id = ? AND w.word = ?`, userID, listID, word).Scan(&img.Data, &img.Thumbnail)
+	if err == sql.ErrNoRows {
+		return Image{}, errNotFound
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("looking up picture of %q: %w", word, err)
+	}
+	return img, nil
+}
+
+func (s *sqlStore) DeleteImage(userID, listID int, word string) error {
+	_, err := s.exec("DELETE FROM images WHERE word_id IN (SELECT id FROM words WHERE user_id = ? AND list_id = ? AND word = ?)", userID, listID, word)
+	if err != nil {
+		return fmt.Errorf("deleting picture of %q: %w", word, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) ImportReference(language, glossLanguage string, next func() (ReferenceEntry, error)) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.dialect.rebind("DELETE FROM reference_words WHERE language = ? AND gloss_language = ?"), language, glossLanguage)
+	if err != nil {
+		return 0, fmt.Errorf("deleting old reference words: %w", err)
+	}
+	insert, err := tx.Prepare(s.dialect.rebind(`
+	INSERT INTO reference_words
+		(language, gloss_language, word, word_key, part_of_speech, ipa, glosses)
+	VALUES
+		(?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return 0, fmt.Errorf("preparing reference word insert: %w", err)
+	}
+	defer insert.Close()
+
+	count := 0
+	for {
+		entry, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		_, err = insert.Exec(language, glossLanguage, entry.Woord, strings.ToLower(entry.Woord), entry.Woordsoort, entry.Uitspraak, strings.Join(entry.Betekenissen, "\n"))
+		if err != nil {
+			return 0, fmt.Errorf("inserting reference word %q: %w", entry.Woord, err)
+		}
+		count++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing reference words: %w", err)
+	}
+	return count, nil
+}
+
+func (s *sqlStore) ReferenceEntries(language, word string) ([]ReferenceEntry, error) {
+	rows, err := s.query(`
+	SELECT
+		word, part_of_speech, ipa, glosses, gloss_language
+	FROM
+		reference_words
+	WHERE
+		language = ? AND word_key = ?
+	ORDER BY
+		id`, language, strings.ToLower(word))
+	if err != nil {
+		return nil, fmt.Errorf("looking up reference word %q: %w", word, err)
+	}
+	defer rows.Close()
+
+	var entries []ReferenceEntry
+	for rows.Next() {
+		var entry ReferenceEntry
+		var glosses string
+		if err := rows.Scan(&entry.Woord, &entry.Woordsoort, &entry.Uitspraak, &glosses, &entry.GlossLanguage); err != nil {
+			return nil, fmt.Errorf("reading reference word row: %w", err)
+		}
+		if glosses != "" {
+			entry.Betekenissen = strings.Split(glosses, "\n")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *sqlStore) ReferenceEntriesOf(language string, words []string) (map[string][]ReferenceEntry, error) {
+	var keys []string
+	seen := map[string]bool{}
+	for _, word := range words {
+		if key := strings.ToLower(word); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	byKey := map[string][]ReferenceEntry{}
+	for start := 0; start < len(keys); start += queryBatch {
+		batch := keys[start:min(start+queryBatch, len(keys))]
+		args := []any{language}
+		for _, key := range batch {
+			args = append(args, key)
+		}
+		rows, err := s.query(`
+		SELECT
+			word_key, word, part_of_speech, ipa, glosses, gloss_language
+		FROM
+			reference_words
+		WHERE
+			language = ? AND word_key IN (?`+strings.Repeat(", ?", len(batch)-1)+`)
+		ORDER BY
+			id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("looking up reference words: %w", err)
+		}
+		for rows.Next() {
+			var key, glosses string
+			var entry ReferenceEntry
+			if err := rows.Scan(&key, &entry.Woord, &entry.Woordsoort, &entry.Uitspraak, &glosses, &entry.GlossLanguage); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading reference word row: %w", err)
+			}
+			if glosses != "" {
+				entry.Betekenissen = strings.Split(glosses, "\n")
+			}
+			byKey[key] = append(byKey[key], entry)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := map[string][]ReferenceEntry{}
+	for _, word := range words {
+		if found, ok := byKey[strings.ToLower(word)]; ok {
+			entries[word] = found
+		}
+	}
+	return entries, nil
+}
+
+func (s *sqlStore) ImportFrequencies(language string, words []WordFrequency) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.dialect.rebind("DELETE FROM word_frequencies WHERE language = ?"), language); err != nil {
+		return fmt.Errorf("deleting old frequencies: %w", err)
+	}
+	insert, err := tx.Prepare(s.dialect.rebind("INSERT INTO word_frequencies (language, word_key, word, frequency, rank) VALUES (?, ?, ?, ?, ?)"))
+	if err != nil {
+		return fmt.Errorf("preparing frequency insert: %w", err)
+	}
+	defer insert.Close()
+	for i, word := range words {
+		if _, err := insert.Exec(language, strings.ToLower(word.Woord), word.Woord, word.Aantal, i+1); err != nil {
+			return fmt.Errorf("inserting frequency of %q: %w", word.Woord, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing frequencies: %w", err)
+	}
+	return nil
+}
+
+// Values looked up in one query, well below the parameter limits of SQLite and PostgreSQL
+const queryBatch = 500
+
+func (s *sqlStore) FrequencyRanks(language string, words []string) (map[string]int, error) {
+	byKey := map[string]int{}
+	for start := 0; start < len(words); start += queryBatch {
+		batch := words[start:min(start+queryBatch, len(words))]
+		args := []any{language}
+		for _, word := range batch {
+			args = append(args, strings.ToLower(word))
+		}
+		rows, err := s.query(`
+		SELECT
+			word_key, rank
+		FROM
+			word_frequencies
+		WHERE
+			language = ? AND word_key IN (?`+strings.Repeat(", ?", len(batch)-1)+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("looking up frequency ranks: %w", err)
+		}
+		for rows.Next() {
+			var key string
+			var rank int
+			if err := rows.Scan(&key, &rank); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading frequency row: %w", err)
+			}
+			byKey[key] = rank
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	ranks := map[string]int{}
+	for _, word := range words {
+		if rank, ok := byKey[strings.ToLower(word)]; ok {
+			ranks[word] = rank
+		}
+	}
+	return ranks, nil
+}
+
+func (s *sqlStore) FrequentWords(language string, after, limit int) ([]WordFrequency, error) {
+	rows, err := s.query(`
+	SELECT
+		word, frequency, rank
+	FROM
+		word_frequencies
+	WHERE
+		language = ? AND rank > ?
+	ORDER BY
+		rank
+	LIMIT ?`, language, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing frequent words: %w", err)
+	}
+	defer rows.Close()
+
+	var words []WordFrequency
+	for rows.Next() {
+		var word WordFrequency
+		if err := rows.Scan(&word.Woord, &word.Aantal, &word.Rang); err != nil {
+			return nil, fmt.Errorf("reading frequency row: %w", err)
+		}
+		words = append(words, word)
+	}
+	return words, rows.Err()
+}
+
+func (s *sqlStore) Lists(userID int) ([]List, error) {
+	rows, err := s.query(`
+	SELECT
+		l.id, l.name, l.source_language, l.target_language, COUNT(w.id)
+	FROM
+		lists l
+	LEFT JOIN
+		words w
+	ON
+		w.list_id = l.id
+	WHERE
+		l.user_id = ?
+	GROUP BY
+		l.id, l.name, l.source_language, l.target_language
+	ORDER BY
+		l.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []List
+	for rows.Next() {
+		var list List
+		if err := rows.Scan(&list.ID, &list.Name, &list.SourceLanguage, &list.TargetLanguage, &list.Count); err != nil {
+			return nil, fmt.Errorf("reading list row: %w", err)
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (s *sqlStore) List(userID, listID int) (List, error) {
+	return s.loadList(s.db, userID, listID)
+}
+
+// The list with its word count, or errNotFound when it isn't the user's
+func (s *sqlStore) loadList(tx querier, userID, listID int) (List, error) {
+	list := List{ID: listID}
+	err := tx.QueryRow(s.dialect.rebind("SELECT name, source_language, target_language, (SELECT COUNT(*) FROM words WHERE list_id = lists.id) FROM lists WHERE id = ? AND user_id = ?"), listID, userID).
+		Scan(&list.Name, &list.SourceLanguage, &list.TargetLanguage, &list.Count)
+	if err == sql.ErrNoRows {
+		return List{}, errNotFound
+	}
+	if err != nil {
+		return List{}, fmt.Errorf("looking up list %d: %w", listID, err)
+	}
+	return list, nil
+}
+
+func (s *sqlStore) DefaultList(userID int) (List, error) {
+	var id sql.NullInt64 // MIN of no rows is NULL
+	if err := s.queryRow("SELECT MIN(id) FROM lists WHERE user_id = ?", userID).Scan(&id); err != nil {
+		return List{}, fmt.Errorf("looking up first list: %w", err)
+	}
+	if id.Valid {
+		return s.List(userID, int(id.Int64))
+	}
+	list, err := s.CreateList(userID, List{Name: defaultListName, SourceLanguage: defaultSourceLanguage, TargetLanguage: defaultTargetLanguage})
+	if err == errListExists {
+		// Made by a concurrent request
+		return s.DefaultList(userID)
+	}
+	return list, err
+}
+
+func (s *sqlStore) CreateList(userID int, list List) (List, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return List{}, fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.checkListName(tx, userID, list); err != nil {
+		return List{}, err
+	}
+	err = tx.QueryRow(s.dialect.rebind("INSERT INTO lists (user_id, name, source_language, target_language) VALUES (?, ?, ?, ?) RETURNING id"),
+		userID, list.Name, list.SourceLanguage, list.TargetLanguage).Scan(&list.ID)
+	if s.dialect.isUniqueViolation(err) {
+		return List{}, errListExists
+	}
+	if err != nil {
+		return List{}, fmt.Errorf("creating list %q: %w", list.Name, err)
+	}
+	list.Count = 0
+	return list, tx.Commit()
+}
+
+func (s *sqlStore) UpdateList(userID int, list List) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.loadList(tx, userID, list.ID); err != nil {
+		return err
+	}
+	if err := s.checkListName(tx, userID, list); err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind("UPDATE lists SET name = ?, source_language = ?, target_language = ? WHERE id = ? AND user_id = ?"),
+		list.Name, list.SourceLanguage, list.TargetLanguage, list.ID, userID)
+	if s.dialect.isUniqueViolation(err) {
+		return errListExists
+	}
+	if err != nil {
+		return fmt.Errorf("updating list %d: %w", list.ID, err)
+	}
+	return tx.Commit()
+}
+
+// errListExists when another list of the user already has the name of list
+func (s *sqlStore) checkListName(tx querier, userID int, list List) error {
+	var id int
+	err := tx.QueryRow(s.dialect.rebind("SELECT id FROM lists WHERE user_id = ? AND name = ? AND id <> ?"), userID, list.Name, list.ID).Scan(&id)
+	if err == nil {
+		return errListExists
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("looking up list %q: %w", list.Name, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) TransferWord(userID int, word string, from, to int, keepOriginal bool, imageQuota int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A copied picture is checked against the quota like an uploaded one
+	if keepOriginal {
+		if err := s.lockUser(tx, userID); err != nil {
+			return err
+		}
+	}
+	wordID, saved, err := s.loadWord(tx, userID, from, word)
+	if err != nil {
+		return err
+	}
+	if _, err := s.loadList(tx, userID, to); err != nil {
+		return err
+	}
+	_, existing, err := s.loadWord(tx, userID, to, word)
+	if err == nil {
+		return &WordExistsError{Existing: existing}
+	}
+	if err != errNotFound {
+		return err
+	}
+
+	if keepOriginal {
+		// The copied picture counts towards the quota like an uploaded one
+		var pictureSize int64
+		err = tx.QueryRow(s.dialect.rebind("SELECT COALESCE(SUM(LENGTH(data) + LENGTH(thumbnail)), 0) FROM images WHERE word_id = ?"), wordID).Scan(&pictureSize)
+		if err != nil {
+			return fmt.Errorf("measuring picture of %q: %w", word, err)
+		}
+		if pictureSize > 0 {
+			used, err := s.imagesSize(tx, userID, 0)
+			if err != nil {
+				return err
+			}
+			if used+pictureSize > imageQuota {
+				return errQuotaExceeded
+			}
+		}
+
+		var copyID int
+		copyID, err = s.insertWord(tx, userID, to, saved)
+		if err == nil {
+			_, err = tx.Exec(s.dialect.rebind("INSERT INTO recordings (word_id, content_type, data) SELECT ?, content_type, data FROM recordings WHERE word_id = ?"), copyID, wordID)
+		}
+		if err == nil {
+			_, err = tx.Exec(s.dialect.rebind("INSERT INTO images (word_id, data, thumbnail) SELECT ?, data, thumbnail FROM images WHERE word_id = ?"), copyID, wordID)
+		}
+	} else {
+		_, err = tx.Exec(s.dialect.rebind("UPDATE words SET list_id = ? WHERE id = ?"), to, wordID)
+	}
+	if err != nil {
+		return fmt.Errorf("moving word %q to list %d: %w", word, to, err)
+	}
+	return tx.Commit()
+}
+
+// The conjugation columns in the order of nullConjugation.dest, prefixed with a table alias when given
+func conjugationColumns(alias string) string {
+	columns := []string{"present_ik", "present_jij", "present_hij", "present_plural", "past_singular", "past_plural", "past_participle", "auxiliary"}
+	if alias != "" {
+		for i := range columns {
+			columns[i] = alias + "." + columns[i]
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// A LIKE condition per conjugated form, joined with OR
+func conjugationFormsLike(alias string, like func(column string) string) string {
+	columns := strings.Split(conjugationColumns(alias), ", ")
+	conditions := make([]string, 0, len(columns))
+	for _, column := range columns[:len(Conjugation{}.forms())] {
+		conditions = append(conditions, like(column))
+	}
+	return strings.Join(conditions, " OR ")
+}
+
+// Scans a conjugation that comes from a LEFT JOIN and may not be there
+type nullConjugation [8]sql.NullString
+
+func (n *nullConjugation) dest() []any {
+	dest := make([]any, len(n))
+	for i := range n {
+		dest[i] = &n[i]
+	}
+	return dest
+}
+
+func (n *nullConjugation) get() *Conjugation {
+	if !n[0].Valid {
+		return nil
+	}
+	return &Conjugation{
+		Ik:                n[0].String,
+		Jij:               n[1].String,
+		Hij:               n[2].String,
+		Meervoud:          n[3].String,
+		VerledenEnkelvoud: n[4].String,
+		VerledenMeervoud:  n[5].String,
+		VoltooidDeelwoord: n[6].String,
+		Hulpwerkwoord:     n[7].String,
+	}
+}
+
+func (s *sqlStore) UserByName(username string) (User, error) {
+	user := User{Username: username}
+	err := s.queryRow("SELECT id, hashed_password FROM users WHERE username = ?", username).Scan(&user.ID, &user.HashedPassword)
+	if err == sql.ErrNoRows {
+		return User{}, errNotFound
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("looking up user %q: %w", username, err)
+	}
+	return user, nil
+}
+
+func (s *sqlStore) CreateUserWithSession(username, hashedPassword, sessionKey string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting db transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(s.dialect.rebind("INSERT INTO users (username, hashed_password) VALUES (?, ?) RETURNING id"), username, hashedPassword).Scan(&userID)
+	if err != nil {
+		return 0, fmt.Errorf("inserting user: %w", err)
+	}
+	_, err = tx.Exec(s.dialect.rebind("INSERT INTO session_keys (user_id, session_key) VALUES (?, ?)"), userID, sessionKey)
+	if err != nil {
+		return 0, fmt.Errorf("inserting session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commiting registration: %w", err)
+	}
+	return userID, nil
+}
+
+func (s *sqlStore) CreateSession(userID int, sessionKey string) error {
+	_, err := s.exec("INSERT INTO session_keys (user_id, session_key) VALUES (?, ?)", userID, sessionKey)
+	if err != nil {
+		return fmt.Errorf("inserting session: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) SessionUser(sessionKey string) (User, error) {
+	query := `
+	SELECT
+		u.id,
+		u.username,
+		u.hashed_password
+	FROM
+		session_keys sk
+	JOIN
+		users u
+	ON
+		sk.user_id = u.id
+	WHERE
+		sk.session_key = ?;
+	`
+	var user User
+	err := s.queryRow(query, sessionKey).Scan(&user.ID, &user.Username, &user.HashedPassword)
+	if err == sql.ErrNoRows {
+		return User{}, errNotFound
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("looking up session: %w", err)
+	}
+	return user, nil
+}
+
+func (s *sqlStore) DeleteSession(sessionKey string) error {
+	_, err := s.exec("DELETE FROM session_keys WHERE session_key = ?", sessionKey)
+	if err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	return nil
+}
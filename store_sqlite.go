@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var sqliteDialect = dialect{
+	name:              "sqlite",
+	driver:            "wordsearch_sqlite3",
+	migrationsDir:     "migrations/sqlite",
+	lower:             "unicode_lower", // lower() of SQLite only knows ASCII letters
+	isUniqueViolation: isSQLiteUniqueViolation,
+	pauseForeignKeys:  true,
+}
+
+// Every connection gets foreign keys turned on, so ON DELETE CASCADE works, and unicode_lower
+func init() {
+	sql.Register(sqliteDialect.driver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA foreign_keys = ON", nil); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("unicode_lower", unicodeLower, true)
+		},
+	})
+}
+
+func unicodeLower(v any) any {
+	if s, ok := v.(string); ok {
+		return strings.ToLower(s)
+	}
+	return nil // NULL, it is only used on text columns
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+func openSQLiteStore(path string) (*sqlStore, error) {
+	db, err := sql.Open(sqliteDialect.driver, path)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStore{db: db, dialect: sqliteDialect}, nil
+}
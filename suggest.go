@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Svuvi/wordsearch/dutch"
+)
+
+// Woordsoort is free text, these are the ways people write down that a word is a noun
+var nounWordTypes = map[string]bool{
+	"zn":                    true,
+	"znw":                   true,
+	"zelfstandig naamwoord": true,
+	"noun":                  true,
+	"substantiv":            true,
+	"nomen":                 true,
+	"n":                     true,
+}
+
+// A noun has an article or says so in its woordsoort
+func isNoun(word Word) bool {
+	if word.Lidwoord != "" {
+		return true
+	}
+	for _, sense := range word.Senses {
+		if nounWordTypes[strings.ToLower(strings.Trim(sense.Woordsoort, " ."))] {
+			return true
+		}
+	}
+	return false
+}
+
+// The conjugation a verb would have if it was weak, with hebben as the usual auxiliary
+func suggestConjugation(infinitive string) (Conjugation, bool) {
+	forms, ok := dutch.ConjugateWeak(infinitive)
+	if !ok {
+		return Conjugation{}, false
+	}
+	return Conjugation{
+		Ik:                forms.Ik,
+		Jij:               forms.Jij,
+		Hij:               forms.Hij,
+		Meervoud:          forms.Meervoud,
+		VerledenEnkelvoud: forms.VerledenEnkelvoud,
+		VerledenMeervoud:  forms.VerledenMeervoud,
+		VoltooidDeelwoord: forms.VoltooidDeelwoord,
+		Hulpwerkwoord:     "hebben",
+	}, true
+}
+
+// A field of the add form that gets a suggested value
+type suggestedField struct {
+	Value     string
+	Suggested string
+}
+
+// Only an empty field or one that still holds the previous suggestion is filled in, what the user typed stays
+func suggestField(current, previous, suggestion string) suggestedField {
+	if current == "" || current == previous {
+		current = suggestion
+	}
+	return suggestedField{Value: current, Suggested: suggestion}
+}
+
+// Fills in the predictable forms of the word being typed into the add form, and what the dictionaries or Wiktionary say about it
+func (c *Context) suggest(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	woord := strings.TrimSpace(q.Get("woord"))
+	entry, err := c.lookupWord(list, woord, q.Get("woordsoort"))
+	if err != nil {
+		return err
+	}
+	woordsoort := q.Get("woordsoort")
+	if woordsoort == "" || woordsoort == q.Get("suggested_woordsoort") {
+		woordsoort = entry.PartOfSpeech
+	}
+	word := Word{Woord: woord, Lidwoord: q.Get("lidwoord"), Senses: []Sense{{Woordsoort: woordsoort}}}
+
+	var plural, diminutive string
+	if woord != "" && isNoun(word) && list.IsDutch() {
+		plural, diminutive = dutch.Plural(woord), dutch.Diminutive(woord)
+	}
+	data := struct {
+		List          List
+		Meervoud      suggestedField
+		Verkleinwoord suggestedField
+		Vervoeging    *Conjugation
+		Woordsoort    suggestedField
+		Uitspraak     suggestedField
+		Vertaling     suggestedField
+		Headwords     []string
+		// Words no dictionary knows are machine translated once the user stops typing, see suggestTranslation
+		Translate bool
+		// Typing in the woordsoort field asks for suggestions too, swapping it would move the cursor
+		KeepWoordsoort bool
+	}{
+		List:           list,
+		Meervoud:       suggestField(q.Get("meervoud"), q.Get("suggested_meervoud"), plural),
+		Verkleinwoord:  suggestField(q.Get("verkleinwoord"), q.Get("suggested_verkleinwoord"), diminutive),
+		Woordsoort:     suggestField(q.Get("woordsoort"), q.Get("suggested_woordsoort"), entry.PartOfSpeech),
+		Uitspraak:      suggestField(q.Get("uitspraak"), q.Get("suggested_uitspraak"), entry.Pronunciation),
+		Vertaling:      suggestField(q.Get("vertaling"), q.Get("suggested_vertaling"), strings.Join(entry.Translations, "; ")),
+		Headwords:      c.dictionaries.complete(list, woord, completions),
+		KeepWoordsoort: r.Header.Get("HX-Trigger-Name") == "woordsoort",
+	}
+	if _, off := c.translator.(noTranslator); !off {
+		data.Translate = woord != "" && len(entry.Translations) == 0
+	}
+	if isVerb(word) && list.IsDutch() {
+		if conjugation, ok := suggestConjugation(woord); ok {
+			data.Vervoeging = &conjugation
+		}
+	}
+	return c.templates.Execute(w, "suggestions.html", data)
+}
+
+// Fills in the machine translation of the word in the add form. The suggestions ask for it after the word has
+// stayed the same for a while, so LibreTranslate isn't sent every prefix and /suggest doesn't wait for it.
+func (c *Context) suggestTranslation(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	woord := strings.TrimSpace(q.Get("woord"))
+	if woord == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), translateTimeout)
+	translation, err := c.translator.Translate(ctx, woord, list.SourceLanguage, list.TargetLanguage)
+	cancel()
+	if err != nil {
+		if err != errNoTranslator {
+			// Nothing is swapped in, the other suggestions are still there
+			log.Printf("Failed to translate %q: %v", woord, err)
+		}
+		return nil
+	}
+	if translation == "" {
+		return nil
+	}
+	data := struct {
+		List      List
+		Vertaling suggestedField
+	}{
+		List:      list,
+		Vertaling: suggestField(q.Get("vertaling"), q.Get("suggested_vertaling"), translation),
+	}
+	return c.templates.Execute(w, "translation-suggestion.html", data)
+}
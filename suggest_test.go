@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestField(t *testing.T) {
+	assert.Equal(t, suggestedField{Value: "huizen", Suggested: "huizen"}, suggestField("", "", "huizen"))
+	assert.Equal(t, suggestedField{Value: "huizen", Suggested: "huizen"}, suggestField("huisen", "huisen", "huizen"), "an old suggestion is replaced")
+	assert.Equal(t, suggestedField{Value: "huisjes", Suggested: "huizen"}, suggestField("huisjes", "huisen", "huizen"), "typed values stay")
+	assert.Equal(t, suggestedField{}, suggestField("huizen", "huizen", ""), "a suggestion goes away with the noun")
+}
+
+func TestIsNoun(t *testing.T) {
+	assert.True(t, isNoun(Word{Lidwoord: "het"}))
+	assert.True(t, isNoun(Word{Senses: []Sense{{Woordsoort: "zn."}}}))
+	assert.False(t, isNoun(Word{Senses: []Sense{{Woordsoort: "ww"}}}))
+}
+
+func TestSuggestHandler(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+
+	get := func(query string) string {
+		req, _ := http.NewRequest("GET", "/suggest?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		appHandler(c.suggest).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := get("woord=huis&lidwoord=het")
+	assert.Contains(t, body, `value="huizen"`)
+	assert.Contains(t, body, `value="huisje"`)
+	assert.NotContains(t, body, "vervoeging")
+
+	body = get("woord=huis&lidwoord=het&meervoud=huisjes")
+	assert.Contains(t, body, `value="huisjes"`, "what the user typed isn't overwritten")
+
+	body = get("woord=werken&woordsoort=ww")
+	assert.Contains(t, body, `name="vervoeging"`)
+	assert.Contains(t, body, "werkte")
+
+	// Adding with the suggestion ticked saves the conjugation too
+	req, _ := http.NewRequest("POST", "/add/", strings.NewReader("woord=werken&woordsoort=ww&vervoeging=suggested"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+	rr := httptest.NewRecorder()
+	appHandler(c.add).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	word, err := c.store.Word(1, 1, "werken")
+	require.NoError(t, err)
+	require.NotNil(t, word.Vervoeging)
+	assert.Equal(t, "gewerkt", word.Vervoeging.VoltooidDeelwoord)
+	assert.Equal(t, "hebben", word.Vervoeging.Hulpwerkwoord)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var responseBadTags = `<p>Tags can only contain letters, digits, - and _, separate them with commas</p>`
+
+// Turns "Hoofdstuk 3, #eten" into [eten hoofdstuk-3]: lowercase, spaces become hyphens, sorted, without duplicates
+func parseTags(s string) ([]string, error) {
+	seen := map[string]bool{}
+	var tags []string
+	for _, field := range strings.Split(s, ",") {
+		tag := strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(field), "#")), "-"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !isTag(tag) {
+			return nil, fmt.Errorf("%q isn't a valid tag", tag)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func isTag(s string) bool {
+	if len(s) > 50 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Splits "#eten appel" into the text to search for and the tags to filter by.
+// Words that aren't valid tags after the # are searched for as they are.
+func parseSearchQuery(query string) (string, []string) {
+	if !strings.Contains(query, "#") {
+		return query, nil
+	}
+	var text, tags []string
+	for _, field := range strings.Fields(query) {
+		tag := strings.ToLower(strings.TrimPrefix(field, "#"))
+		if strings.HasPrefix(field, "#") && tag != "" && isTag(tag) {
+			tags = append(tags, tag)
+			continue
+		}
+		text = append(text, field)
+	}
+	return strings.Join(text, " "), tags
+}
+
+// Sends the tag editor for a saved word
+func (c *Context) tagsForm(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	word, err := c.store.Word(user_id, list.ID, r.PathValue("woord"))
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Woord string
+		List  int
+		Tags  string
+	}{Woord: word.Woord, List: list.ID, Tags: strings.Join(word.Tags, ", ")}
+	return c.templates.Execute(w, "tags-form.html", data)
+}
+
+func (c *Context) saveTags(w http.ResponseWriter, r *http.Request) error {
+	_, authorised, user_id := c.isAutorised(r)
+	if !authorised {
+		return errNotAuthorised
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	tags, err := parseTags(r.PostFormValue("tags"))
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadTags, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+
+	err = c.store.SetTags(user_id, list.ID, r.PathValue("woord"), tags)
+	if err == errNotFound {
+		return newHTTPError(http.StatusNotFound, "<p>No such word</p>", nil)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		tags  []string
+		valid bool
+	}{
+		{name: "Empty", input: "", tags: nil, valid: true},
+		{name: "Sorted and lowercase", input: "Thema, boek", tags: []string{"boek", "thema"}, valid: true},
+		{name: "Spaces become hyphens", input: " hoofdstuk  3 ", tags: []string{"hoofdstuk-3"}, valid: true},
+		{name: "Hash and duplicates", input: "#eten, eten,,", tags: []string{"eten"}, valid: true},
+		{name: "Markup", input: "<b>eten</b>", valid: false},
+		{name: "Quotes", input: `eten"`, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := parseTags(tt.input)
+			if tt.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.tags, tags)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	text, tags := parseSearchQuery("de  appel")
+	assert.Equal(t, "de  appel", text, "queries without tags are kept as they are")
+	assert.Nil(t, tags)
+
+	text, tags = parseSearchQuery("#Eten appel #hoofdstuk-3")
+	assert.Equal(t, "appel", text)
+	assert.Equal(t, []string{"eten", "hoofdstuk-3"}, tags)
+
+	text, tags = parseSearchQuery("# #<b>")
+	assert.Equal(t, "# #<b>", text, "things that aren't tags are searched for")
+	assert.Nil(t, tags)
+}
+
+func TestTagsHandlers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+
+	router := http.NewServeMux()
+	router.Handle("POST /", appHandler(c.search))
+	router.Handle("POST /add/", appHandler(c.add))
+	router.Handle("GET /tags/{woord}", appHandler(c.tagsForm))
+	router.Handle("POST /tags/{woord}", appHandler(c.saveTags))
+	do := func(method, path, form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=appel&vertaling=apple&tags=eten, hoofdstuk 1").Code)
+	require.Equal(t, http.StatusOK, do("POST", "/add/", "woord=fiets&vertaling=bicycle&tags=hoofdstuk 1").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/add/", "woord=huis&tags=<i>").Code)
+
+	body := do("POST", "/", "search=%23eten").Body.String()
+	assert.Contains(t, body, "appel")
+	assert.NotContains(t, body, "fiets")
+	assert.Contains(t, body, "#hoofdstuk-1", "tags are shown as chips")
+
+	body = do("POST", "/", "search=&filter_tag=hoofdstuk-1").Body.String()
+	assert.Contains(t, body, "appel")
+	assert.Contains(t, body, "fiets")
+	assert.Contains(t, body, `<option value="hoofdstuk-1" selected>`)
+
+	assert.Contains(t, do("GET", "/tags/appel", "").Body.String(), `value="eten, hoofdstuk-1"`)
+	require.Equal(t, http.StatusOK, do("POST", "/tags/fiets", "tags=vervoer").Code)
+	body = do("POST", "/", "search=%23vervoer").Body.String()
+	assert.Contains(t, body, "fiets")
+	assert.Equal(t, http.StatusNotFound, do("POST", "/tags/huis", "tags=wonen").Code)
+}
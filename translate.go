@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoTranslator = errors.New("no machine translation configured")
+
+const (
+	// How long the add form waits for a translation, the suggestion is left out when it takes longer
+	translateTimeout = 3 * time.Second
+	// Translations kept in memory, the oldest is dropped first
+	translationCacheSize = 10_000
+)
+
+// Translator suggests a translation for words the dictionaries don't have
+type Translator interface {
+	// Translates text between the languages of the BCP-47 tags. Returns errNoTranslator when machine translation is off.
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// Machine translation is off unless the deployment names a LibreTranslate server
+func newTranslator(cfg Config) Translator {
+	if cfg.TranslateURL == "" {
+		return noTranslator{}
+	}
+	next := libreTranslator{url: strings.TrimSuffix(cfg.TranslateURL, "/"), apiKey: cfg.TranslateAPIKey, client: http.DefaultClient}
+	return newCachedTranslator(next, translationCacheSize)
+}
+
+// noTranslator is used when machine translation is off
+type noTranslator struct{}
+
+func (noTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	return "", errNoTranslator
+}
+
+// libreTranslator talks to a LibreTranslate server, or anything with the same /translate endpoint
+type libreTranslator struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+func (l libreTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	// LibreTranslate knows languages, not regions
+	body, err := json.Marshal(libreTranslateRequest{Q: text, Source: baseLanguage(from), Target: baseLanguage(to), Format: "text", APIKey: l.apiKey})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", l.url+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling LibreTranslate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result libreTranslateResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("reading LibreTranslate response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LibreTranslate answered %s: %s", resp.Status, result.Error)
+	}
+	return strings.TrimSpace(result.TranslatedText), nil
+}
+
+type translationKey struct {
+	text, from, to string
+}
+
+// cachedTranslator remembers what next translated, so a word is only sent once while it is being typed and added
+type cachedTranslator struct {
+	next Translator
+	size int
+
+	mu           sync.Mutex
+	translations map[translationKey]string
+	order        []translationKey // oldest first
+}
+
+func newCachedTranslator(next Translator, size int) *cachedTranslator {
+	return &cachedTranslator{next: next, size: size, translations: map[translationKey]string{}}
+}
+
+func (c *cachedTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	key := translationKey{text, from, to}
+	c.mu.Lock()
+	translation, ok := c.translations[key]
+	c.mu.Unlock()
+	if ok {
+		return translation, nil
+	}
+
+	// Failures aren't cached, the server may be back next time
+	translation, err := c.next.Translate(ctx, text, from, to)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.translations[key]; !ok {
+		if len(c.order) >= c.size {
+			delete(c.translations, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.translations[key] = translation
+	return translation, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A LibreTranslate server that knows a few Dutch words
+func newStubLibreTranslate(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req libreTranslateRequest
+		if r.Method != "POST" || r.URL.Path != "/translate" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(libreTranslateResponse{Error: "bad request"})
+			return
+		}
+		if req.APIKey != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(libreTranslateResponse{Error: "Invalid API key"})
+			return
+		}
+		english := map[string]string{"fiets": "bicycle", "auto": "car"}
+		if req.Source == "nl" && req.Target == "en" && req.Format == "text" && english[req.Q] != "" {
+			json.NewEncoder(w).Encode(libreTranslateResponse{TranslatedText: english[req.Q]})
+			return
+		}
+		json.NewEncoder(w).Encode(libreTranslateResponse{TranslatedText: req.Q})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLibreTranslator(t *testing.T) {
+	var calls atomic.Int32
+	server := newStubLibreTranslate(t, &calls)
+
+	translator := libreTranslator{url: server.URL, apiKey: "secret", client: server.Client()}
+	translation, err := translator.Translate(context.Background(), "fiets", "nl-BE", "en-GB")
+	require.NoError(t, err)
+	assert.Equal(t, "bicycle", translation, "regions are left out")
+
+	translator.apiKey = "wrong"
+	_, err = translator.Translate(context.Background(), "fiets", "nl", "en")
+	assert.ErrorContains(t, err, "Invalid API key")
+}
+
+func TestNewTranslator(t *testing.T) {
+	cfg := DefaultConfig()
+	_, err := newTranslator(cfg).Translate(context.Background(), "fiets", "nl", "en")
+	assert.Equal(t, errNoTranslator, err, "off by default")
+
+	var calls atomic.Int32
+	cfg.TranslateURL = newStubLibreTranslate(t, &calls).URL + "/"
+	cfg.TranslateAPIKey = "secret"
+	translation, err := newTranslator(cfg).Translate(context.Background(), "fiets", "nl", "en")
+	require.NoError(t, err)
+	assert.Equal(t, "bicycle", translation)
+}
+
+func TestCachedTranslator(t *testing.T) {
+	var calls atomic.Int32
+	server := newStubLibreTranslate(t, &calls)
+	translator := newCachedTranslator(libreTranslator{url: server.URL, apiKey: "secret", client: server.Client()}, 2)
+
+	for range 3 {
+		translation, err := translator.Translate(context.Background(), "fiets", "nl", "en")
+		require.NoError(t, err)
+		assert.Equal(t, "bicycle", translation)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	translator.Translate(context.Background(), "huis", "nl", "en")
+	translator.Translate(context.Background(), "fiets", "nl", "de")
+	assert.Equal(t, int32(3), calls.Load(), "other languages are translated again")
+	translator.Translate(context.Background(), "fiets", "nl", "en")
+	assert.Equal(t, int32(4), calls.Load(), "the oldest translation was dropped")
+	assert.Len(t, translator.translations, 2)
+
+	failing := newCachedTranslator(libreTranslator{url: server.URL, apiKey: "wrong", client: server.Client()}, 2)
+	failing.Translate(context.Background(), "fiets", "nl", "en")
+	failing.Translate(context.Background(), "fiets", "nl", "en")
+	assert.Equal(t, int32(6), calls.Load(), "failures aren't cached")
+}
+
+func TestSuggestTranslation(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	var calls atomic.Int32
+	server := newStubLibreTranslate(t, &calls)
+	c.translator = libreTranslator{url: server.URL, apiKey: "secret", client: server.Client()}
+	_, err = c.store.ImportReference("nl", "en", readWiktextract(strings.NewReader(testDump), "nl"))
+	require.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+
+	get := func(handler appHandler, query string) string {
+		req, _ := http.NewRequest("GET", "/suggest?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := get(c.suggest, "woord=auto")
+	assert.Contains(t, body, `hx-get="/suggest/translation"`, "a word no dictionary has is translated later")
+	assert.NotContains(t, body, `value="car"`)
+	assert.Equal(t, int32(0), calls.Load(), "typing doesn't wait for the server")
+	assert.Contains(t, get(c.suggestTranslation, "woord=auto"), `autocomplete="off" value="car"`)
+	assert.Contains(t, get(c.suggestTranslation, "woord=auto&vertaling=automobile&suggested_vertaling="), `value="automobile"`, "a typed translation stays")
+
+	body = get(c.suggest, "woord=huis")
+	assert.Contains(t, body, `autocomplete="off" value="house; home; household"`)
+	assert.NotContains(t, body, `hx-get="/suggest/translation"`, "words Wiktionary has aren't sent")
+	assert.Equal(t, int32(2), calls.Load())
+
+	c.translator = libreTranslator{url: server.URL, apiKey: "wrong", client: server.Client()}
+	assert.Empty(t, get(c.suggestTranslation, "woord=auto"), "a failing server leaves the suggestions as they are")
+
+	c.translator = noTranslator{}
+	assert.NotContains(t, get(c.suggest, "woord=auto"), `hx-get="/suggest/translation"`, "nothing is asked for without a server")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Svuvi/wordsearch/dictionary"
+)
+
+// How many glosses of a reference word are suggested as its translation
+const referenceGlosses = 3
+
+// ReferenceEntry is a word from a Wiktionary dump, not owned by any user
+type ReferenceEntry struct {
+	Woord         string
+	Woordsoort    string // part of speech as Wiktextract writes it: noun, verb, adj
+	Uitspraak     string // IPA without slashes or brackets
+	Betekenissen  []string
+	GlossLanguage string // the language of the Wiktionary the glosses come from
+}
+
+// One line of a Wiktextract JSONL dump, only the fields we keep
+type wiktextractEntry struct {
+	Word     string `json:"word"`
+	LangCode string `json:"lang_code"`
+	POS      string `json:"pos"`
+	Sounds   []struct {
+		IPA string `json:"ipa"`
+	} `json:"sounds"`
+	Senses []struct {
+		Glosses []string          `json:"glosses"`
+		FormOf  []json.RawMessage `json:"form_of"`
+		AltOf   []json.RawMessage `json:"alt_of"`
+	} `json:"senses"`
+}
+
+func (e wiktextractEntry) reference() ReferenceEntry {
+	entry := ReferenceEntry{Woord: e.Word, Woordsoort: e.POS}
+	for _, sound := range e.Sounds {
+		if sound.IPA != "" {
+			entry.Uitspraak = strings.Trim(sound.IPA, "/[] ")
+			break
+		}
+	}
+	seen := map[string]bool{}
+	for _, sense := range e.Senses {
+		// "plural of huis" tells nothing about the meaning, only the pronunciation of such entries is useful
+		if len(sense.Glosses) == 0 || len(sense.FormOf) > 0 || len(sense.AltOf) > 0 {
+			continue
+		}
+		// Subsenses repeat the glosses of their parent before their own
+		gloss := strings.TrimSpace(sense.Glosses[len(sense.Glosses)-1])
+		if gloss != "" && !seen[gloss] {
+			seen[gloss] = true
+			entry.Betekenissen = append(entry.Betekenissen, gloss)
+		}
+	}
+	return entry
+}
+
+// Reads the entries of one language from a Wiktextract dump, one JSON object per line.
+// Returns io.EOF after the last one, like ImportReference wants.
+func readWiktextract(r io.Reader, language string) func() (ReferenceEntry, error) {
+	reader := bufio.NewReader(r)
+	line := 0
+	return func() (ReferenceEntry, error) {
+		for {
+			data, err := reader.ReadBytes('\n')
+			if len(data) == 0 && err != nil {
+				return ReferenceEntry{}, err
+			}
+			line++
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+			var entry wiktextractEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return ReferenceEntry{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			// Dumps of all of Wiktionary have every language and redirects without a word
+			if entry.Word != "" && baseLanguage(entry.LangCode) == language {
+				return entry.reference(), nil
+			}
+		}
+	}
+}
+
+// wordsearch import-wiktionary <language> <dump.jsonl> [gloss language]
+// Replaces the reference words of the language by the ones in a Wiktextract dump, which can be gzipped.
+// The glosses are English unless the dump comes from another Wiktionary.
+func runImportWiktionary(cfg Config, args []string, out io.Writer) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("import-wiktionary: expected a language, a dump file and optionally the language of the glosses")
+	}
+	language, err := parseLanguage(args[0])
+	if err != nil {
+		return fmt.Errorf("import-wiktionary: %w", err)
+	}
+	glossLanguage := "en"
+	if len(args) == 3 {
+		if glossLanguage, err = parseLanguage(args[2]); err != nil {
+			return fmt.Errorf("import-wiktionary: %w", err)
+		}
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var dump io.Reader = f
+	if strings.HasSuffix(args[1], ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", args[1], err)
+		}
+		dump = zr
+	}
+
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := migrateToLatest(store); err != nil {
+		return err
+	}
+
+	count, err := store.ImportReference(baseLanguage(language), baseLanguage(glossLanguage), readWiktextract(dump, baseLanguage(language)))
+	if err != nil {
+		return fmt.Errorf("importing %s: %w", args[1], err)
+	}
+	fmt.Fprintf(out, "imported %d %s words\n", count, languageName(language))
+	return nil
+}
+
+// What the dictionaries and the Wiktionary reference know about a word being added to the list.
+// Dictionaries come first, the reference fills in what they leave empty, like the IPA most dictionaries don't have.
+// Of several reference entries the one with the part of speech the user typed is taken.
+func (c *Context) lookupWord(list List, woord, woordsoort string) (dictionary.Entry, error) {
+	var references []ReferenceEntry
+	if woord != "" {
+		var err error
+		if references, err = c.store.ReferenceEntries(baseLanguage(list.SourceLanguage), woord); err != nil {
+			return dictionary.Entry{}, err
+		}
+	}
+	return c.withReference(list, woord, woordsoort, references), nil
+}
+
+// What the dictionaries know about a word, with what they leave empty filled in from the word's reference entries
+func (c *Context) withReference(list List, woord, woordsoort string, references []ReferenceEntry) dictionary.Entry {
+	entry, _ := c.dictionaries.lookup(list, woord)
+	if len(references) == 0 {
+		return entry
+	}
+	reference := references[0]
+	for _, r := range references {
+		if woordsoort != "" && strings.EqualFold(r.Woordsoort, woordsoort) {
+			reference = r
+			break
+		}
+	}
+
+	if entry.Headword == "" {
+		entry.Headword = reference.Woord
+	}
+	if entry.Pronunciation == "" {
+		entry.Pronunciation = reference.Uitspraak
+	}
+	if entry.PartOfSpeech == "" {
+		entry.PartOfSpeech = reference.Woordsoort
+	}
+	// English glosses are only a translation for lists into English
+	if len(entry.Translations) == 0 && reference.GlossLanguage == baseLanguage(list.TargetLanguage) {
+		entry.Translations = reference.Betekenissen[:min(len(reference.Betekenissen), referenceGlosses)]
+	}
+	return entry
+}
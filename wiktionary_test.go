@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A few lines in the shape of a kaikki.org dump, with a German word and a redirect mixed in like in dumps of all languages
+const testDump = `{"word": "fiets", "lang": "Dutch", "lang_code": "nl", "pos": "noun", "sounds": [{"audio": "Nl-fiets.ogg"}, {"ipa": "/fits/"}], "senses": [{"glosses": ["bicycle"]}, {"glosses": ["bicycle", "a bike ride"]}]}
+{"word": "fiets", "lang": "Dutch", "lang_code": "nl", "pos": "verb", "senses": [{"glosses": ["inflection of fietsen"], "form_of": [{"word": "fietsen"}]}]}
+
+{"title": "Fiets", "redirect": "fiets"}
+{"word": "Haus", "lang": "German", "lang_code": "de", "pos": "noun", "sounds": [{"ipa": "[haʊ̯s]"}], "senses": [{"glosses": ["house"]}]}
+{"word": "huis", "lang": "Dutch", "lang_code": "nl", "pos": "noun", "sounds": [{"ipa": "/ɦœys/"}], "senses": [{"glosses": ["house"]}, {"glosses": ["home"]}, {"glosses": ["household"]}, {"glosses": ["housing"]}]}
+`
+
+func TestReadWiktextract(t *testing.T) {
+	next := readWiktextract(strings.NewReader(testDump), "nl")
+	var entries []ReferenceEntry
+	for {
+		entry, err := next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+
+	assert.Equal(t, []ReferenceEntry{
+		{Woord: "fiets", Woordsoort: "noun", Uitspraak: "fits", Betekenissen: []string{"bicycle", "a bike ride"}},
+		{Woord: "fiets", Woordsoort: "verb"},
+		{Woord: "huis", Woordsoort: "noun", Uitspraak: "ɦœys", Betekenissen: []string{"house", "home", "household", "housing"}},
+	}, entries)
+
+	_, err := readWiktextract(strings.NewReader("{not json\n"), "nl")()
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestImportWiktionary(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DatabasePath = filepath.Join(t.TempDir(), "words.db")
+	dump := filepath.Join(t.TempDir(), "dutch.jsonl.gz")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(testDump))
+	zw.Close()
+	require.NoError(t, os.WriteFile(dump, gz.Bytes(), 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, runImportWiktionary(cfg, []string{"nl", dump}, &out))
+	assert.Equal(t, "imported 3 Nederlands words\n", out.String())
+	// Importing again replaces the words instead of adding them twice
+	require.NoError(t, runImportWiktionary(cfg, []string{"nl", dump}, &out))
+
+	store, err := openStore(cfg)
+	require.NoError(t, err)
+	defer store.Close()
+	entries, err := store.ReferenceEntries("nl", "Fiets")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "en", entries[0].GlossLanguage)
+
+	assert.Error(t, runImportWiktionary(cfg, []string{"nl"}, &out))
+	assert.Error(t, runImportWiktionary(cfg, []string{"not a language!", dump}, &out))
+	assert.Error(t, runImportWiktionary(cfg, []string{"nl", filepath.Join(t.TempDir(), "missing.jsonl")}, &out))
+}
+
+func TestSuggestFromWiktionary(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+	_, err = c.store.ImportReference("nl", "en", readWiktextract(strings.NewReader(testDump), "nl"))
+	require.NoError(t, err)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Dutch')")
+	db.Exec("INSERT INTO lists (user_id, name, source_language, target_language) VALUES (1, 'Nederlands-Deutsch', 'nl', 'de')")
+
+	get := func(query string) string {
+		req, _ := http.NewRequest("GET", "/suggest?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		appHandler(c.suggest).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := get("list=1&woord=huis")
+	assert.Contains(t, body, `name="uitspraak" placeholder="uitspraak" autocomplete="off" value="ɦœys"`)
+	assert.Contains(t, body, `autocomplete="off" value="house; home; household"`, "only the first glosses")
+	assert.Contains(t, body, `value="huizen"`, "Wiktionary says it is a noun")
+
+	body = get("list=1&woord=fiets&woordsoort=verb")
+	assert.NotContains(t, body, "bicycle", "the entry with the typed part of speech is taken")
+
+	body = get("list=2&woord=huis")
+	assert.Contains(t, body, `value="ɦœys"`, "the pronunciation doesn't depend on the translation language")
+	assert.NotContains(t, body, "house", "English glosses aren't German translations")
+}
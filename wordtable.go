@@ -2,38 +2,85 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
-	"text/template"
 
 	_ "net/http/pprof"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
+var responseEmptyWord = `<p>The word can't be empty!</p>`
+
 type Word struct {
+	List             int // id of the list the word is in
 	Woord            string
-	WoordHighlighted string
-	Woordsoort       string
+	WoordHighlighted template.HTML
 	Uitspraak        string
-	Vertaling        string
+	Senses           []Sense
+
+	// Noun forms, empty for other parts of speech
+	Lidwoord      string // de, het or de/het
+	Meervoud      string
+	Verkleinwoord string
+
+	Vervoeging  *Conjugation // nil until a conjugation is saved for a verb
+	Tags        []string     // sorted tag names
+	Voorbeelden []Example    // example sentences in the order they were added
+	Opname      bool         // whether a pronunciation recording was uploaded
+	Afbeelding  bool         // whether a picture was uploaded
+	Rang        int          // rank in the frequency list of the language, 0 when it isn't in it
+}
+
+// Sense is one meaning of a word, a word like "bank" can have several
+type Sense struct {
+	Position   int // order of the sense within the word, starting from 1
+	Woordsoort string
+	Vertaling  string
+	Notes      string
+
+	// Vertaling and Notes with the search query in bold, for the table
+	VertalingHighlighted template.HTML
+	NotesHighlighted     template.HTML
 }
 
 type TableTmplData struct {
 	Words *[]Word
 	Count struct {
-		Total   int
+		Total   int // words in the list
 		Matched int
+		All     int // words in all lists
 	}
+	// For the list selector and the tag filter, which are sent along with every table so they know about new lists and tags
+	List   List
+	Lists  []List
+	Tags   []string
+	Speech bool // generated pronunciation for words without a recording
+	tableView
 }
 
+// How the table shows the words, as opposed to which words it shows
+type tableView struct {
+	TagFilter string // the tag picked in the tag filter, it is also in the filter's tags
+	Sort      string // empty for the order the words were added in, sortAlphabetical or sortFrequency
+}
+
+// Sorts by the collation of the list's language, so ä comes with a in German and ij with i in Dutch
+const sortAlphabetical = "alpha"
+
+// Sorts the most frequent words of the language first, by the imported frequency list
+const sortFrequency = "freq"
+
 func NewTableTmplData(words *[]Word, countTotal int) TableTmplData {
 	return TableTmplData{
 		Words: words,
 		Count: struct {
 			Total   int
 			Matched int
+			All     int
 		}{
 			Total:   countTotal,
 			Matched: len(*words),
@@ -41,133 +88,268 @@ func NewTableTmplData(words *[]Word, countTotal int) TableTmplData {
 	}
 }
 
-func highlightQuery(text, query string) string {
-	if query == "" {
-		return text
+// Folds new into an already saved word: the first new sense goes into the first saved one, the rest are appended.
+// Empty fields are filled in, fields that say something different are kept side by side.
+func mergeWords(saved, new Word) Word {
+	merged := saved
+	merged.Uitspraak = mergeField(saved.Uitspraak, new.Uitspraak)
+	merged.Lidwoord = mergeArticle(saved.Lidwoord, new.Lidwoord)
+	merged.Meervoud = mergeField(saved.Meervoud, new.Meervoud)
+	merged.Verkleinwoord = mergeField(saved.Verkleinwoord, new.Verkleinwoord)
+	merged.Senses = append([]Sense(nil), saved.Senses...)
+
+	newSenses := new.Senses
+	if len(merged.Senses) > 0 && len(newSenses) > 0 {
+		first := &merged.Senses[0]
+		first.Woordsoort = mergeField(first.Woordsoort, newSenses[0].Woordsoort)
+		first.Vertaling = mergeField(first.Vertaling, newSenses[0].Vertaling)
+		first.Notes = mergeField(first.Notes, newSenses[0].Notes)
+		newSenses = newSenses[1:]
 	}
-	// Escape special HTML characters in the query
-	escapedQuery := template.HTMLEscapeString(query)
-	// Replace occurrences of the query with highlighted version
-	highlighted := strings.ReplaceAll(text, escapedQuery, "<b>"+escapedQuery+"</b>")
-	return highlighted
+	merged.Senses = append(merged.Senses, newSenses...)
+	return merged
 }
 
-func (c *Context) renderWordsTable(search string, user_id int) []byte {
-	t := template.Must(template.ParseFiles("./templates/table.html"))
-	var words []Word
-
-	searchString := "%" + search + "%"
-	q := `
-	SELECT
-		word, word_type, pronunciation, translation
-	FROM
-		words
-	WHERE
-		user_id LIKE ? AND (word LIKE ? OR  translation LIKE ?);
-	`
-	rows, _ := c.db.Query(q, user_id, searchString, searchString)
-	for rows.Next() {
-		var word Word
-		rows.Scan(&word.Woord, &word.Woordsoort, &word.Uitspraak, &word.Vertaling)
+func mergeField(saved, new string) string {
+	switch {
+	case new == "" || strings.Contains(saved, new):
+		return saved
+	case saved == "":
+		return new
+	default:
+		return saved + "; " + new
+	}
+}
 
-		word.WoordHighlighted = highlightQuery(word.Woord, search)
-		word.Vertaling = highlightQuery(word.Vertaling, search)
+// Escapes text and puts every occurrence of the query in bold
+func highlightQuery(text, query string) template.HTML {
+	if query == "" {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	parts := strings.Split(text, query)
+	for i, part := range parts {
+		parts[i] = template.HTMLEscapeString(part)
+	}
+	return template.HTML(strings.Join(parts, "<b>"+template.HTMLEscapeString(query)+"</b>"))
+}
 
-		words = append(words, word)
-		// log.Printf("{Woord: %s, Woordsoort: %s, Uitspraak: %s, Vertaling: %s}", word.Woord, word.Woordsoort, word.Uitspraak, word.Vertaling)
+func (c *Context) renderWordsTable(filter WordFilter, view tableView, user_id int) ([]byte, error) {
+	search := filter.Search
+	words, err := c.store.SearchWords(user_id, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range words {
+		words[i].WoordHighlighted = highlightQuery(words[i].Woord, search)
+		for j := range words[i].Senses {
+			sense := &words[i].Senses[j]
+			sense.VertalingHighlighted = highlightQuery(sense.Vertaling, search)
+			sense.NotesHighlighted = highlightQuery(sense.Notes, search)
+		}
 	}
 
-	var wordsTotal int
-	c.db.QueryRow("SELECT COUNT(*) FROM words WHERE user_id = ?", user_id).Scan(&wordsTotal)
+	wordsTotal, err := c.store.CountWords(user_id, filter.List)
+	if err != nil {
+		return nil, err
+	}
 
 	data := NewTableTmplData(&words, wordsTotal)
+	data.tableView = view
+	data.Speech = canSynthesize(c.synthesizer)
+	data.Tags, err = c.store.Tags(user_id)
+	if err != nil {
+		return nil, err
+	}
+	data.Lists, err = c.store.Lists(user_id)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range data.Lists {
+		data.Count.All += list.Count
+		if list.ID == filter.List {
+			data.List = list
+		}
+	}
+	if err := c.rankWords(words, data.List); err != nil {
+		return nil, err
+	}
+	switch view.Sort {
+	case sortAlphabetical:
+		sortWords(words, data.List.SourceLanguage)
+	case sortFrequency:
+		sortByFrequency(words)
+	}
 
 	var wordsTable bytes.Buffer
-	t.Execute(&wordsTable, data)
-	return wordsTable.Bytes()
+	if err := c.templates.Execute(&wordsTable, "table.html", data); err != nil {
+		return nil, err
+	}
+	return wordsTable.Bytes(), nil
 }
 
-func (c *Context) search(w http.ResponseWriter, r *http.Request) {
+func (c *Context) search(w http.ResponseWriter, r *http.Request) error {
 	_, authorised, user_id := c.isAutorised(r)
 	if !authorised {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("<p>You are not autorized. <a href=\"/login\">Log in</a></p>"))
-		return
+		return errNotAuthorised
 	}
 
-	r.ParseForm()
-	query := r.PostForm["search"][0]
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	query := normalizeText(r.PostFormValue("search"), list.SourceLanguage)
+	text, tags := parseSearchQuery(query)
+	tagFilter := r.PostFormValue("filter_tag")
+	if tagFilter != "" {
+		tags = append(tags, tagFilter)
+	}
+	filter := WordFilter{
+		List:    list.ID,
+		Search:  text,
+		Article: r.PostFormValue("filter_lidwoord"),
+		Tags:    tags,
+	}
 
-	w.Write(c.renderWordsTable(query, user_id))
+	view := tableView{TagFilter: tagFilter, Sort: r.PostFormValue("sort")}
+	table, err := c.renderWordsTable(filter, view, user_id)
+	if err != nil {
+		return err
+	}
+	w.Write(table)
 	log.Printf("Rendered a table for query \"%s\"", query)
+	return nil
 }
 
-func (c *Context) add(w http.ResponseWriter, r *http.Request) {
+func (c *Context) add(w http.ResponseWriter, r *http.Request) error {
 	_, authorised, user_id := c.isAutorised(r)
 	if !authorised {
-		// w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("<p>You are not autorized. <a href=\"/login\">Log in</a></p>"))
-		return
+		return errNotAuthorised
 	}
 
-	r.ParseForm()
-	log.Println(r.PostForm)
+	if err := r.ParseForm(); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadForm, err)
+	}
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+
+	newWord := Word{Woord: r.PostFormValue("woord"),
+		Uitspraak:     r.PostFormValue("uitspraak"),
+		Lidwoord:      r.PostFormValue("lidwoord"),
+		Meervoud:      strings.TrimSpace(r.PostFormValue("meervoud")),
+		Verkleinwoord: strings.TrimSpace(r.PostFormValue("verkleinwoord")),
+		Senses: []Sense{{
+			Woordsoort: r.PostFormValue("woordsoort"),
+			Vertaling:  r.PostFormValue("vertaling"),
+			Notes:      r.PostFormValue("aantekening"),
+		}}}
+	normalizeWord(&newWord, list)
 
-	if len(r.PostForm["woord"][0]) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Println("The user tried to add an empty string as a word")
-		return
+	if len(newWord.Woord) == 0 {
+		return newHTTPError(http.StatusBadRequest, responseEmptyWord, nil)
+	}
+	if err := validateNounForms(newWord, list.Articles()); err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadNounForms, err)
 	}
+	tags, err := parseTags(r.PostFormValue("tags"))
+	if err != nil {
+		return newHTTPError(http.StatusBadRequest, responseBadTags, err)
+	}
+	newWord.Tags = tags
 
-	newWord := Word{Woord: r.PostForm["woord"][0],
-		Woordsoort: r.PostForm["woordsoort"][0],
-		Uitspraak:  r.PostForm["uitspraak"][0],
-		Vertaling:  r.PostForm["vertaling"][0]}
+	onConflict := ConflictMode(r.PostFormValue("on_conflict"))
+	switch onConflict {
+	case conflictAsk, conflictMerge, conflictOverwrite, conflictKeepBoth:
+	default:
+		return newHTTPError(http.StatusBadRequest, responseBadForm, fmt.Errorf("unknown conflict mode %q", onConflict))
+	}
 
-	statement, _ := c.db.Prepare("INSERT OR REPLACE INTO words (user_id, word, word_type, pronunciation, translation) VALUES (?, ?, ?, ?, ?)")
-	_, err := statement.Exec(user_id, newWord.Woord, newWord.Woordsoort, newWord.Uitspraak, newWord.Vertaling)
+	err = c.store.AddWord(user_id, list.ID, newWord, onConflict)
+	var exists *WordExistsError
+	if errors.As(err, &exists) {
+		// Let the user pick what to do, the buttons in the template send the form again with on_conflict set
+		var conflict bytes.Buffer
+		data := struct {
+			New      Word
+			Existing Word
+		}{New: newWord, Existing: exists.Existing}
+		if err := c.templates.Execute(&conflict, "conflict.html", data); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusConflict)
+		w.Write(conflict.Bytes())
+		return nil
+	}
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	if r.PostFormValue("vervoeging") == "suggested" && list.IsDutch() {
+		if err := c.saveSuggestedConjugation(user_id, list.ID, newWord); err != nil {
+			return err
+		}
+	}
+	// Words added from a text keep the sentence they were found in
+	if zin := strings.TrimSpace(normalizeText(r.PostFormValue("voorbeeld"), list.SourceLanguage)); zin != "" && len([]rune(zin)) <= maxExampleLength {
+		if _, err := c.store.AddExample(user_id, list.ID, newWord.Woord, Example{Zin: zin}); err != nil {
+			return err
+		}
 	}
 	w.WriteHeader(http.StatusOK)
+	return nil
 }
 
-func (c *Context) delete(w http.ResponseWriter, r *http.Request) {
+func (c *Context) delete(w http.ResponseWriter, r *http.Request) error {
 	username, authorised, user_id := c.isAutorised(r)
 	if !authorised {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("<p>You are not autorized. <a href=\"/login\">Log in</a></p>"))
-		return
+		return errNotAuthorised
 	}
 
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
 	word := r.PathValue("woord")
-	log.Printf("Deleting word: '%s', user: '%s'", word, username)
+	log.Printf("Deleting word: '%s', list: %d, user: '%s'", word, list.ID, username)
 
-	var query string
-	var err error
 	if word == "" {
 		log.Println("Deleting empty string word")
-		query = "DELETE FROM words WHERE user_id = ? (word = ? OR word IS NULL);" // for some reason doesnt work here, but does work in SQLite DB browser
-		_, err = c.db.Exec(query, user_id, word)
-	} else {
-		query = "DELETE FROM words WHERE user_id = ? AND word = $1;"
-		_, err = c.db.Exec(query, user_id, word)
 	}
 
-	if err != nil {
-		log.Printf("Error when deleting word: '%s", word)
-		log.Print(err)
+	// Without a sense the whole word goes
+	position := 0
+	if s := r.URL.Query().Get("sense"); s != "" {
+		position, err = strconv.Atoi(s)
+		if err != nil {
+			return newHTTPError(http.StatusBadRequest, "<p>Bad sense number</p>", err)
+		}
 	}
+	return c.store.DeleteWord(user_id, list.ID, word, position)
 }
 
-func (c *Context) indexPage(w http.ResponseWriter, r *http.Request) {
-	username, authorised, _ := c.isAutorised(r)
+func (c *Context) indexPage(w http.ResponseWriter, r *http.Request) error {
+	username, authorised, user_id := c.isAutorised(r)
 	if !authorised {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil
 	}
 
-	data := struct{ Username string }{Username: username}
-	t := template.Must(template.ParseFiles("./templates/index.html"))
-	t.Execute(w, data)
+	list, err := c.requestList(r, user_id)
+	if err != nil {
+		return err
+	}
+	lists, err := c.store.Lists(user_id)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Username  string
+		List      List
+		Lists     []List
+		Languages []languageOption
+	}{Username: username, List: list, Lists: lists, Languages: languageOptions()}
+	return c.templates.Execute(w, "index.html", data)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,15 +10,19 @@ import (
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func setupTestDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", ":memory:")
+	db, err := sql.Open(sqliteDialect.driver, ":memory:")
 	if err != nil {
 		return nil, err
 	}
+	// Every connection to :memory: is a separate database, so make sure there is only one
+	db.SetMaxOpenConns(1)
 
-	_, err = db.Exec(DatabaseSchema)
+	err = migrateToLatest(&sqlStore{db: db, dialect: sqliteDialect})
 	if err != nil {
 		return nil, err
 	}
@@ -25,10 +30,24 @@ func setupTestDB() (*sql.DB, error) {
 	return db, nil
 }
 
+func newTestContext(db *sql.DB) *Context {
+	cfg := DefaultConfig()
+	cfg.BcryptCost = bcrypt.MinCost
+	templatesFS, _, err := assetFS(cfg)
+	if err != nil {
+		panic(err)
+	}
+	templates, err := NewTemplates(templatesFS, false)
+	if err != nil {
+		panic(err)
+	}
+	return &Context{store: &sqlStore{db: db, dialect: sqliteDialect}, config: cfg, templates: templates, synthesizer: noSynthesizer{}, translator: noTranslator{}}
+}
+
 func TestHighlightQuery(t *testing.T) {
 	text := "Hello world"
 	query := "world"
-	expected := "Hello <b>world</b>"
+	expected := template.HTML("Hello <b>world</b>")
 	result := highlightQuery(text, query)
 	assert.Equal(t, expected, result)
 
@@ -36,12 +55,15 @@ func TestHighlightQuery(t *testing.T) {
 	expected = "Hello world"
 	result = highlightQuery(text, query)
 	assert.Equal(t, expected, result)
+
+	assert.Equal(t, template.HTML("&lt;script&gt;<b>a&amp;b</b>&lt;/script&gt;"), highlightQuery("<script>a&b</script>", "a&b"), "the text is escaped too")
+	assert.Equal(t, template.HTML("fish &amp; chips"), highlightQuery("fish & chips", "amp"), "escaping doesn't make matches")
 }
 
 func TestNewTableTmplData(t *testing.T) {
 	words := []Word{
-		{Woord: "word1", Woordsoort: "noun", Uitspraak: "word1", Vertaling: "word1"},
-		{Woord: "word2", Woordsoort: "verb", Uitspraak: "word2", Vertaling: "word2"},
+		{Woord: "word1", Uitspraak: "word1", Senses: []Sense{{Woordsoort: "noun", Vertaling: "word1"}}},
+		{Woord: "word2", Uitspraak: "word2", Senses: []Sense{{Woordsoort: "verb", Vertaling: "word2"}}},
 	}
 	data := NewTableTmplData(&words, 10)
 	assert.Equal(t, 2, data.Count.Matched)
@@ -54,15 +76,17 @@ func TestRenderWordsTable(t *testing.T) {
 		t.Fatalf("Failed to setup test database: %v", err)
 	}
 	defer db.Close()
-	c := &Context{db: db}
+	c := newTestContext(db)
 
 	// Insert mock data
 	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
-	db.Exec("INSERT INTO words (user_id, word, word_type, pronunciation, translation) VALUES (1, 'hello', 'noun', 'hello', 'hallo')")
+	db.Exec("INSERT INTO words (user_id, list_id, word, pronunciation) VALUES (1, 1, 'hello', 'hello')")
+	db.Exec("INSERT INTO senses (word_id, position, part_of_speech, translation) VALUES (1, 1, 'noun', 'hallo')")
 
 	search := "hello"
 	userID := 1
-	result := c.renderWordsTable(search, userID)
+	result, err := c.renderWordsTable(WordFilter{Search: search}, tableView{}, userID)
+	assert.NoError(t, err)
 
 	assert.Contains(t, string(result), "<b>hello</b>")
 	assert.Contains(t, string(result), "hallo")
@@ -74,7 +98,7 @@ func TestSearchHandler(t *testing.T) {
 		t.Fatalf("Failed to setup test database: %v", err)
 	}
 	defer db.Close()
-	c := &Context{db: db}
+	c := newTestContext(db)
 
 	// Insert mock data
 	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
@@ -85,7 +109,7 @@ func TestSearchHandler(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
 	rr := httptest.NewRecorder()
 
-	c.search(rr, req)
+	appHandler(c.search).ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
 }
 
@@ -95,7 +119,7 @@ func TestAddHandler(t *testing.T) {
 		t.Fatalf("Failed to setup test database: %v", err)
 	}
 	defer db.Close()
-	c := &Context{db: db}
+	c := newTestContext(db)
 
 	// Insert mock data
 	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
@@ -107,8 +131,12 @@ func TestAddHandler(t *testing.T) {
 	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
 	rr := httptest.NewRecorder()
 
-	c.add(rr, req)
+	appHandler(c.add).ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
+	saved, err := c.store.SearchWords(1, WordFilter{})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, "testword", saved[0].Woord)
 }
 
 func TestDeleteHandler(t *testing.T) {
@@ -117,17 +145,79 @@ func TestDeleteHandler(t *testing.T) {
 		t.Fatalf("Failed to setup test database: %v", err)
 	}
 	defer db.Close()
-	c := &Context{db: db}
+	c := newTestContext(db)
 
 	// Insert mock data
 	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
 	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
-	db.Exec("INSERT INTO words (user_id, word, word_type, pronunciation, translation) VALUES (1, 'deleteword', 'noun', 'deleteword', 'deleteword')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	db.Exec("INSERT INTO words (user_id, list_id, word, pronunciation) VALUES (1, 1, 'deleteword', 'deleteword')")
+	db.Exec("INSERT INTO senses (word_id, position, part_of_speech, translation) VALUES (1, 1, 'noun', 'deleteword')")
 
-	req, _ := http.NewRequest("POST", "/delete/deleteword", nil)
+	router := http.NewServeMux()
+	router.Handle("DELETE /delete/{woord}", appHandler(c.delete))
+	req, _ := http.NewRequest("DELETE", "/delete/deleteword?list=1", nil)
 	req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
 	rr := httptest.NewRecorder()
 
-	c.delete(rr, req)
+	router.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusOK, rr.Code)
+	_, err = c.store.Word(1, 1, "deleteword")
+	assert.Equal(t, errNotFound, err, "the word is gone")
+}
+
+func TestMergeWords(t *testing.T) {
+	saved := Word{Woord: "bank", Senses: []Sense{{Position: 1, Woordsoort: "zn", Vertaling: "couch"}}}
+
+	merged := mergeWords(saved, Word{Woord: "bank", Uitspraak: "bɑŋk", Senses: []Sense{
+		{Woordsoort: "zn", Vertaling: "sofa", Notes: "furniture"},
+		{Vertaling: "bank"},
+	}})
+	assert.Equal(t, Word{Woord: "bank", Uitspraak: "bɑŋk", Senses: []Sense{
+		{Position: 1, Woordsoort: "zn", Vertaling: "couch; sofa", Notes: "furniture"},
+		{Vertaling: "bank"},
+	}}, merged)
+	assert.Equal(t, "couch", saved.Senses[0].Vertaling, "the saved word isn't modified")
+
+	merged = mergeWords(saved, Word{Woord: "bank"})
+	assert.Equal(t, saved, merged, "empty fields don't erase anything")
+}
+
+func TestAddHandlerConflict(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer db.Close()
+	c := newTestContext(db)
+
+	db.Exec("INSERT INTO users (username, hashed_password) VALUES (?, ?)", "user1", "hashed_password")
+	db.Exec("INSERT INTO session_keys (user_id, session_key) VALUES (1, 'valid_session_key')")
+	db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Mijn woorden')")
+	db.Exec("INSERT INTO words (user_id, list_id, word, pronunciation) VALUES (1, 1, 'bank', '')")
+	db.Exec("INSERT INTO senses (word_id, position, part_of_speech, translation) VALUES (1, 1, 'zn', 'couch')")
+
+	post := func(form string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/add/", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session_key", Value: "valid_session_key"})
+		rr := httptest.NewRecorder()
+		appHandler(c.add).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post("woord=bank&vertaling=bank")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "couch")
+	assert.Contains(t, rr.Body.String(), `"on_conflict": "keep-both"`)
+
+	rr = post("woord=bank&vertaling=bank&on_conflict=keep-both")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM senses").Scan(&count)
+	assert.Equal(t, 2, count)
+
+	rr = post("woord=bank&on_conflict=whatever")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }